@@ -0,0 +1,126 @@
+package puff
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// contextKey is the key the puff Context is stored under in the request context
+// while a net/http middleware runs.
+type contextKey struct{}
+
+// FromHTTPMiddleware converts a net/http middleware into a puff Middleware so the
+// many existing func(http.Handler) http.Handler middlewares can be used with Use.
+//
+// Example usage:
+//
+//	app.Use(puff.FromHTTPMiddleware(handlers.CompressHandler))
+//
+// Changes the middleware makes to the request or the response writer are visible to
+// the rest of the chain through the Context.
+func FromHTTPMiddleware(m func(http.Handler) http.Handler) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		h := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, ok := r.Context().Value(contextKey{}).(*Context)
+			if !ok {
+				// the middleware replaced the request context; continue with a new Context.
+				c = NewContext(w, r)
+			}
+			c.ResponseWriter = w
+			c.Request = r
+			next(c)
+		}))
+		return func(c *Context) {
+			r := c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, c))
+			h.ServeHTTP(c.ResponseWriter, r)
+		}
+	}
+}
+
+// ToHTTPMiddleware converts a puff Middleware into a net/http middleware, so puff
+// middlewares can wrap any http.Handler.
+func ToHTTPMiddleware(m Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := m(func(c *Context) {
+			next.ServeHTTP(&statusWriter{ResponseWriter: c.ResponseWriter, c: c}, c.Request)
+		})
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := NewContext(w, r)
+			// errors reported by the middleware, such as recovered panics, are sent
+			// like they are for puff routes.
+			defer c.handleError()
+			h(c)
+		})
+	}
+}
+
+// mountHandler adapts h to a handler that strips the router's full prefix and prefix
+// from the request path before serving it, like http.StripPrefix. The path h sees is
+// the rest captured by the catch-all param of the mount, so it does not depend on how
+// the prefix was matched.
+func mountHandler(h http.Handler) func(*Context) {
+	return func(c *Context) {
+		r := c.Request.Clone(c.Request.Context())
+		r.URL.Path = "/" + c.catchAll
+		r.URL.RawPath = rawSuffix(c.Request.URL.RawPath, c.catchAll)
+		h.ServeHTTP(&statusWriter{ResponseWriter: c.ResponseWriter, c: c}, r)
+	}
+}
+
+// rawSuffix returns the end of the escaped path raw that unescapes to rest, with a
+// leading slash. It returns "" if raw is empty or has no such end.
+func rawSuffix(raw string, rest string) string {
+	if raw == "" {
+		return ""
+	}
+	for i := len(raw) - 1; i >= 0; i-- {
+		if raw[i] != '/' {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(raw[i+1:]); err == nil && unescaped == rest {
+			return raw[i:]
+		}
+	}
+	return ""
+}
+
+// statusWriter records the status code written by a net/http handler on the Context,
+// so middlewares such as Logging see it.
+type statusWriter struct {
+	http.ResponseWriter
+	c *Context
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.c.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.c.statusCode == 0 {
+		w.c.statusCode = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("the underlying http.ResponseWriter does not support hijacking")
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use with http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -8,6 +8,20 @@ import (
 	"log/slog"
 	"net/http"
 	"reflect"
+	"slices"
+)
+
+// TrailingSlashPolicy controls how a request path that differs from a route only by
+// a trailing slash is handled.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashStrict treats /pizza and /pizza/ as different paths.
+	TrailingSlashStrict TrailingSlashPolicy = iota
+	// TrailingSlashRedirect redirects to the path the route is registered with.
+	TrailingSlashRedirect
+	// TrailingSlashMatch serves the route for both paths.
+	TrailingSlashMatch
 )
 
 type PuffApp struct {
@@ -29,6 +43,27 @@ type PuffApp struct {
 	OpenAPI *OpenAPI
 	// TLSConfig to pass into the underlying http.Server
 	TLSConfig *tls.Config
+	// TrailingSlash is the policy for paths that only differ from a route by a trailing slash.
+	TrailingSlash TrailingSlashPolicy
+	// CleanPath redirects requests with repeated slashes or . and .. elements to the clean path.
+	CleanPath bool
+	// CaseInsensitive matches the static segments of routes regardless of case.
+	CaseInsensitive bool
+	// Versioning enables API versioning. See Versioning.
+	Versioning *Versioning
+	// EnableFieldsCopy copies the input bound for a request to the Fields of routes
+	// registered with a method of Router, for handlers that still read their input from
+	// there. The Fields are shared by every request, so the copy races with concurrent
+	// requests: only enable it while migrating those handlers to Input.
+	EnableFieldsCopy bool
+	// ErrorHandler sends the errors reported for requests, including the errors returned
+	// by Endpoint handlers and panics recovered by the Panic middleware. Defaults to
+	// DefaultErrorHandler.
+	ErrorHandler ErrorHandler
+	// errors maps errors to status codes. See RegisterError.
+	errors []errorMapping
+	// built reports whether Build has prepared the app for serving.
+	built bool
 	// the underlying server that powers Puff.
 	server *http.Server
 }
@@ -40,6 +75,15 @@ func (a *PuffApp) IncludeRouter(r *Router) {
 	a.RootRouter.IncludeRouter(r)
 }
 
+// Group creates a sub-router under prefix in the PuffApp's root router. See Router.Group.
+//
+// Parameters:
+// - prefix: The URL path prefix of the group.
+// - fn: A function configuring the group, e.g. registering its routes and middlewares.
+func (a *PuffApp) Group(prefix string, fn func(g *Router)) *Router {
+	return a.RootRouter.Group(prefix, fn)
+}
+
 // Use registers a middleware function to be used by the root router of the PuffApp.
 // The middleware will be appended to the list of middlewares in the root router.
 //
@@ -68,9 +112,34 @@ func (a *PuffApp) addOpenAPIRoutes() {
 	}
 
 	// Provides JSON OpenAPI Schema.
+	spec := a.OpenAPI.spec
+	var versionURLs []SwaggerUIURL
+	if a.Versioning != nil && len(a.Versioning.Versions) > 0 {
+		// one spec per version, and the default version's spec as the app's.
+		for i, version := range a.Versioning.Versions {
+			versionSpec, err := a.OpenAPIFor(version.Name)
+			if err != nil {
+				slog.Error("Failed to generate the OpenAPI spec", slog.String("version", version.Name), slog.Any("error", err))
+				return
+			}
+			url := a.DocsURL + "/" + version.Name + ".json"
+			docsRouter.Get("/"+version.Name+".json", nil, func(c *Context) {
+				c.SendResponse(GenericResponse{
+					Content:     string(*versionSpec.spec),
+					ContentType: "application/json",
+				})
+			})
+			versionURLs = append(versionURLs, SwaggerUIURL{URL: url, Name: "v" + version.Name})
+			if i == a.Versioning.defaultIndex() {
+				spec = versionSpec.spec
+			}
+		}
+		// the latest version is shown first.
+		slices.Reverse(versionURLs)
+	}
 	docsRouter.Get(".json", nil, func(c *Context) {
 		res := GenericResponse{
-			Content:     string(*a.OpenAPI.spec),
+			Content:     string(*spec),
 			ContentType: "application/json",
 		}
 		c.SendResponse(res)
@@ -87,6 +156,7 @@ func (a *PuffApp) addOpenAPIRoutes() {
 				Filter:          true,
 				RequestDuration: false,
 				FaviconURL:      "https://fav.farm/💨",
+				URLs:            versionURLs,
 			}
 			a.OpenAPI.SwaggerUIConfig = &swaggerConfig
 		}
@@ -97,44 +167,87 @@ func (a *PuffApp) addOpenAPIRoutes() {
 	})
 
 	a.IncludeRouter(&docsRouter)
+	// the docs routes are not part of the app's API, so the app's middlewares are not applied.
+	err := docsRouter.compile(nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// compile prepares every route of the app, in routers nested at any depth, for serving.
+// See Router.compile.
+func (a *PuffApp) compile() error {
+	if a.RootRouter.puff != a {
+		a.RootRouter.puff = a
+	}
+	return a.RootRouter.compile(nil)
 }
 
-// attachMiddlewares recursively applies middlewares to all routes within a router.
-// This function traverses through the router's sub-routers and routes, applying the
-// middleware functions in the given order.
+// Update changes the routes of a running app. fn may register routes and routers, and
+// remove them with Router.Unregister and Router.ExcludeRouter. Once fn returns, the new
+// routes are compiled and the dispatch table is swapped atomically: requests in flight
+// finish on the routes they matched, and new requests see every change made by fn at once.
+// Calls to Update are serialized.
 //
-// Parameters:
-// - middleware_combo: A pointer to a slice of Middleware to be applied.
-// - router: The router whose middlewares and routes should be processed.
-func attachMiddlewares(middleware_combo *[]Middleware, router *Router) {
-	for _, m := range router.Middlewares {
-		nmc := append(*middleware_combo, *m)
-		middleware_combo = &nmc
+// If a new route fails to compile or conflicts with another route, the changes made by
+// fn are rolled back, the app keeps serving the previous routes and the error is returned.
+// Routes are compiled once, so middlewares added to a router in fn only apply to the
+// routes added after them. Routes added at runtime are not added to the OpenAPI documentation.
+//
+// Example usage:
+//
+//	plugin := puff.NewRouter("Plugin", "/plugins/pizza")
+//	plugin.Get("/menu", nil, handler)
+//	err := app.Update(func() { app.IncludeRouter(plugin) })
+//	// later
+//	err = app.Update(func() { app.RootRouter.ExcludeRouter(plugin) })
+//
+// Before the app is serving, Update just runs fn.
+func (a *PuffApp) Update(fn func()) error {
+	a.RootRouter.mu.Lock()
+	defer a.RootRouter.mu.Unlock()
+	if a.RootRouter.dispatch.Load() == nil {
+		fn()
+		return nil
 	}
-	for _, route := range router.Routes {
-		for _, m := range *middleware_combo {
-			route.Handler = (m)(route.Handler)
-		}
+	previous := a.RootRouter.snapshot()
+	fn()
+	err := a.compile()
+	if err == nil {
+		err = a.RootRouter.buildTree()
 	}
-	for _, router := range router.Routers {
-		attachMiddlewares((middleware_combo), router)
+	if err != nil {
+		previous.restore()
+		return err
 	}
+	return nil
 }
 
-// patchAllRoutes applies middlewares to all routes and sub-routers in the root router
-// of the PuffApp. It also patches the routes of each router to ensure they have been
-// processed for middlewares.
-func (a *PuffApp) patchAllRoutes() {
-	a.RootRouter.patchRoutes()
-	for _, r := range a.RootRouter.Routers {
-		r.patchRoutes()
+// Build prepares the app for serving: it compiles every route, adds OpenAPI documentation
+// routes (if available) and builds the routing tree. Routes that cannot be compiled, or
+// that conflict with each other, are reported in the returned error. ListenAndServe builds
+// the app; call Build to serve RootRouter with a server of your own, such as an
+// httptest.Server. Once an app is built, Build does nothing; use Update to change its routes.
+func (a *PuffApp) Build() error {
+	a.RootRouter.mu.Lock()
+	defer a.RootRouter.mu.Unlock()
+	if a.built {
+		return nil
+	}
+	if err := a.compile(); err != nil {
+		return err
+	}
+	a.addOpenAPIRoutes()
+	if err := a.RootRouter.buildTree(); err != nil {
+		return err
 	}
-	attachMiddlewares(&[]Middleware{}, a.RootRouter)
+	a.built = true
+	return nil
 }
 
 // ListenAndServe starts the PuffApp server on the specified address.
-// Before starting, it patches all routes, adds OpenAPI documentation routes (if available),
-// and sets up logging.
+// Before starting, it builds the app with Build and sets up logging. Routes that cannot
+// be compiled, or that conflict with each other, are reported in the returned error.
 //
 // If TLS certificates are provided (TLSPublicCertFile and TLSPrivateKeyFile), the server
 // starts with TLS enabled; otherwise, it runs a standard HTTP server.
@@ -142,12 +255,19 @@ func (a *PuffApp) patchAllRoutes() {
 // Parameters:
 // - listenAddr: The address the server will listen on (e.g., ":8080").
 func (a *PuffApp) ListenAndServe(listenAddr string) error {
+	if a.Logger == nil {
+		a.Logger = slog.Default()
+	}
 	slog.SetDefault(a.Logger)
-	a.patchAllRoutes()
-	a.addOpenAPIRoutes()
+	err := a.Build()
+	if err != nil {
+		return err
+	}
+	if a.Logger.Enabled(context.Background(), slog.LevelDebug) {
+		slog.Debug("Serving routes:\n" + a.RouteTable())
+	}
 	slog.Debug(fmt.Sprintf("Running Puff 💨 on %s", listenAddr))
 	slog.Debug(fmt.Sprintf("Visit docs 💨 on %s", fmt.Sprintf("http://localhost%s%s", listenAddr, a.DocsURL)))
-	var err error
 
 	httpServer := &http.Server{
 		Addr:      listenAddr,
@@ -215,6 +335,69 @@ func (a *PuffApp) Delete(path string, fields any, handleFunc func(*Context)) *Ro
 	return a.RootRouter.Delete(path, fields, handleFunc)
 }
 
+// Head registers an HTTP HEAD route in the PuffApp's root router.
+// GET routes already answer HEAD requests, so this is only needed to override that behavior.
+//
+// Parameters:
+// - path: The URL path of the route.
+// - fields: Optional fields associated with the route.
+// - handleFunc: The handler function that will be executed when the route is accessed.
+func (a *PuffApp) Head(path string, fields any, handleFunc func(*Context)) *Route {
+	return a.RootRouter.Head(path, fields, handleFunc)
+}
+
+// Options registers an HTTP OPTIONS route in the PuffApp's root router.
+// OPTIONS requests are otherwise answered automatically with the Allow header.
+//
+// Parameters:
+// - path: The URL path of the route.
+// - fields: Optional fields associated with the route.
+// - handleFunc: The handler function that will be executed when the route is accessed.
+func (a *PuffApp) Options(path string, fields any, handleFunc func(*Context)) *Route {
+	return a.RootRouter.Options(path, fields, handleFunc)
+}
+
+// Any registers a route for every HTTP method in the PuffApp's root router.
+//
+// Parameters:
+// - path: The URL path of the route.
+// - fields: Optional fields associated with the route.
+// - handleFunc: The handler function that will be executed when the route is accessed.
+func (a *PuffApp) Any(path string, fields any, handleFunc func(*Context)) *Route {
+	return a.RootRouter.Any(path, fields, handleFunc)
+}
+
+// Match registers a route for each of the provided HTTP methods in the PuffApp's root router.
+//
+// Parameters:
+// - methods: The HTTP methods the route is served for.
+// - path: The URL path of the route.
+// - fields: Optional fields associated with the route.
+// - handleFunc: The handler function that will be executed when the route is accessed.
+func (a *PuffApp) Match(methods []string, path string, fields any, handleFunc func(*Context)) *Route {
+	return a.RootRouter.Match(methods, path, fields, handleFunc)
+}
+
+// Mount serves an http.Handler under prefix in the PuffApp's root router.
+// The prefix is stripped from the request path before h runs.
+//
+// Parameters:
+// - prefix: The URL path prefix to serve h under.
+// - h: The handler to serve.
+func (a *PuffApp) Mount(prefix string, h http.Handler) {
+	a.RootRouter.Mount(prefix, h)
+}
+
+// Static serves the files in root under prefix in the PuffApp's root router.
+//
+// Parameters:
+// - prefix: The URL path prefix to serve the files under.
+// - root: A path to a directory or an fs.FS, such as an embed.FS.
+// - config: Optional configuration for index files, listings and SPA fallback.
+func (a *PuffApp) Static(prefix string, root any, config ...StaticConfig) {
+	a.RootRouter.Static(prefix, root, config...)
+}
+
 // WebSocket registers a WebSocket route in the PuffApp's root router.
 // This route allows the server to handle WebSocket connections at the specified path.
 //
@@ -233,6 +416,23 @@ func (a *PuffApp) AllRoutes() []*Route {
 	return a.RootRouter.AllRoutes()
 }
 
+// URLFor builds the path of the route named name, including the prefixes of its routers.
+// params are substituted, in order, for the params in the route path and are escaped.
+// It returns an error if no route has the name or if params do not fit the route path.
+//
+// Example usage:
+//
+//	app.Get("/pizza/{id:int}", nil, handler).WithName("pizza.get")
+//	url, err := app.URLFor("pizza.get", 42) // "/pizza/42"
+func (a *PuffApp) URLFor(name string, params ...any) (string, error) {
+	for _, route := range a.AllRoutes() {
+		if route.Name == name {
+			return buildPath(route.completePath(), params)
+		}
+	}
+	return "", fmt.Errorf("no route named %s", name)
+}
+
 func (a *PuffApp) GenerateOpenAPISpec() {
 	if reflect.ValueOf(a.OpenAPI).IsZero() {
 		paths, tags := a.GeneratePathsTags()
@@ -243,7 +443,7 @@ func (a *PuffApp) GenerateOpenAPISpec() {
 				Title:       a.Name,
 				Description: "<h4>Application built via Puff Framework</h4>",
 			},
-			Servers:  []Server{},
+			Servers:  a.GenerateServers(),
 			Tags:     tags,
 			Paths:    paths,
 			Security: []SecurityRequirement{},
@@ -274,18 +474,53 @@ func (a *PuffApp) GenerateOpenAPISpec() {
 // GeneratePathsTags is a helper function to auto-define OpenAPI tags and paths if you would like to customize OpenAPI schema.
 // Returns (paths, tagss) to populate the 'Paths' and 'Tags' attribute of OpenAPI
 func (a *PuffApp) GeneratePathsTags() (Paths, []Tag) {
+	return a.generatePathsTags(func(*Route) bool { return true })
+}
+
+// generatePathsTags generates the OpenAPI paths and tags of the documented routes for
+// which include returns true.
+func (a *PuffApp) generatePathsTags(include func(*Route) bool) (Paths, []Tag) {
 	var tags []Tag
 	var tagNames []string
 	var paths = make(Paths)
-	for _, route := range a.RootRouter.Routes {
+	for _, route := range a.RootRouter.AllRoutes() {
+		if route.hidden || !include(route) {
+			continue
+		}
 		addRoute(route, &tags, &tagNames, &paths)
 	}
-	for _, router := range a.RootRouter.Routers {
-		for _, route := range router.Routes {
-			addRoute(route, &tags, &tagNames, &paths)
+	return paths, tags
+}
+
+// GenerateServers is a helper function to auto-define the OpenAPI servers. Every Host pattern
+// used by a router is listed as a separate server, after the server the app itself is served on.
+func (a *PuffApp) GenerateServers() []Server {
+	servers := []Server{}
+	seen := []string{}
+	for _, route := range a.AllRoutes() {
+		host := route.resolveHost()
+		if host == "" || slices.Contains(seen, host) {
+			continue
+		}
+		seen = append(seen, host)
+		pattern, err := parseHostPattern(host)
+		if err != nil {
+			continue
 		}
+		servers = append(servers, pattern.server(a.scheme()))
 	}
-	return paths, tags
+	if len(servers) > 0 {
+		servers = append([]Server{{URL: "/", Description: a.Name}}, servers...)
+	}
+	return servers
+}
+
+// scheme returns the URL scheme the app is served with.
+func (a *PuffApp) scheme() string {
+	if a != nil && a.TLSPublicCertFile != "" && a.TLSPrivateKeyFile != "" {
+		return "https"
+	}
+	return "http"
 }
 
 // GenerateDefinitions is a helper function to auto-define OpenAPI tags and paths if you would like to customize OpenAPI schema.
@@ -24,6 +24,21 @@ type Context struct {
 	// WebSocket will be nil if the route does not use websockets.
 	WebSocket  *websocket.Conn
 	statusCode int
+	// hostParams are the values captured from the host by the Host pattern of the router.
+	hostParams map[string]string
+	// params are the path param values captured while matching the request path.
+	params []string
+	// catchAll is the rest of the path captured by the catch-all param of the route, if
+	// it has one, e.g. "css/site.css" for "/static/{filepath...}".
+	catchAll string
+	// input is a pointer to the input struct bound for the request, if the route has one.
+	input any
+	// apiVersion is the name of the API version the request was resolved to.
+	apiVersion string
+	// err is the error reported for the request with Error.
+	err error
+	// puff is the app serving the request. It is nil for routers served outside of an app.
+	puff *PuffApp
 }
 
 func NewContext(w http.ResponseWriter, r *http.Request) *Context {
@@ -80,6 +95,12 @@ func (ctx *Context) GetQueryParam(k string) string {
 	return ctx.Request.URL.Query().Get(k)
 }
 
+// GetHostParam retrives the value captured from the request host by the param
+// named k in the router's Host pattern. If not found, it will return an empty string.
+func (ctx *Context) GetHostParam(k string) string {
+	return ctx.hostParams[k]
+}
+
 // GetFormValue retrives the value of a form key named k.
 // If not found, it will return an empty string.
 func (ctx *Context) GetFormValue(k string) string {
@@ -96,6 +117,14 @@ func (ctx *Context) GetCookie(k string) string {
 	return cookie.Value
 }
 
+// URLFor builds the path of the route named name with params. See PuffApp.URLFor.
+func (ctx *Context) URLFor(name string, params ...any) (string, error) {
+	if ctx.puff == nil {
+		return "", fmt.Errorf("URLFor is only available for requests served by a PuffApp")
+	}
+	return ctx.puff.URLFor(name, params...)
+}
+
 // SetCookie writes a new cookie to the request with key "k" and
 // value "v". Invalid cookies will be silently dropped. Invalid
 // characters will also be silently dropped. Ex. SetCookie with value
@@ -145,6 +174,9 @@ func (c *Context) SendResponse(res Response) {
 	}
 
 	c.SetContentType(res.GetContentType())
+	if hw, ok := res.(headerWriter); ok {
+		hw.writeHeaders(c)
+	}
 
 	if res.GetStatusCode() != 0 { // don't write statusCode for certain content types
 		c.SetStatusCode(res.GetStatusCode())
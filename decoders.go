@@ -0,0 +1,103 @@
+package puff
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// decoder decodes the values of params of a type.
+type decoder struct {
+	decode func(value string, format string) (any, error)
+	schema Schema
+}
+
+var (
+	decodersMu sync.RWMutex
+	// decoders are the decoders of types that cannot be decoded from their kind alone.
+	decoders = map[reflect.Type]decoder{}
+)
+
+func init() {
+	RegisterDecoder(func(value string, format string) (time.Time, error) {
+		if format == "" {
+			format = time.RFC3339
+		}
+		return time.Parse(format, value)
+	}, Schema{Type: "string", Format: "date-time", Example: "2024-06-01T12:00:00Z"})
+	RegisterDecoder(func(value string, _ string) (time.Duration, error) {
+		return time.ParseDuration(value)
+	}, Schema{Type: "string", Format: "duration", Example: "1h30m"})
+	RegisterDecoder(func(value string, _ string) (uuid.UUID, error) {
+		return uuid.Parse(value)
+	}, Schema{Type: "string", Format: "uuid", Example: "f47ac10b-58cc-0372-8567-0e02b2c3d479"})
+}
+
+// RegisterDecoder registers decode as the decoder of the params of type T, and schema
+// as the OpenAPI schema of T. format is the value of the format tag of the field, if
+// any. A decoder replaces any previous decoder of T, including the built-in decoders
+// of time.Time, time.Duration and uuid.UUID.
+//
+// Types without a decoder that implement encoding.TextUnmarshaler are decoded with
+// UnmarshalText and documented as strings.
+//
+// Example usage:
+//
+//	puff.RegisterDecoder(func(value string, format string) (Money, error) {
+//		return ParseMoney(value)
+//	}, puff.Schema{Type: "string", Pattern: `^\d+\.\d{2}$`})
+func RegisterDecoder[T any](decode func(value string, format string) (T, error), schema Schema) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[reflect.TypeFor[T]()] = decoder{
+		decode: func(value string, format string) (any, error) {
+			return decode(value, format)
+		},
+		schema: schema,
+	}
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// decoderFor returns the decoder of t, if it has one or implements
+// encoding.TextUnmarshaler.
+func decoderFor(t reflect.Type) (decoder, bool) {
+	decodersMu.RLock()
+	d, ok := decoders[t]
+	decodersMu.RUnlock()
+	if ok {
+		return d, true
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return decoder{
+			decode: func(value string, _ string) (any, error) {
+				v := reflect.New(t)
+				if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+					return nil, err
+				}
+				return v.Elem().Interface(), nil
+			},
+			schema: Schema{Type: "string"},
+		}, true
+	}
+	return decoder{}, false
+}
+
+// decodeField decodes value into field with the decoder of its type. It reports
+// whether the type has a decoder.
+func decodeField(value string, format string, field reflect.Value) (bool, error) {
+	d, ok := decoderFor(field.Type())
+	if !ok {
+		return false, nil
+	}
+	decoded, err := d.decode(value, format)
+	if err != nil {
+		return true, fmt.Errorf("type error: the value %s cant be used as the expected type %s: %s", value, field.Type().String(), err.Error())
+	}
+	field.Set(reflect.ValueOf(decoded))
+	return true, nil
+}
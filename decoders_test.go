@@ -0,0 +1,141 @@
+package puff_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThePuffProject/puff"
+	"github.com/google/uuid"
+)
+
+type Cents int
+
+type DeliveryInput struct {
+	ID       uuid.UUID     `kind:"path"`
+	At       time.Time     `kind:"query" name:"at"`
+	Day      time.Time     `kind:"query" name:"day" format:"2006-01-02" required:"false"`
+	Within   time.Duration `kind:"query" name:"within" required:"false"`
+	Tip      Cents         `kind:"query" name:"tip" required:"false"`
+	ClientIP netip.Addr    `kind:"header" name:"X-Client-IP" required:"false"`
+}
+
+func TestDecoders(t *testing.T) {
+	puff.RegisterDecoder(func(value string, _ string) (Cents, error) {
+		dollars, cents, _ := strings.Cut(value, ".")
+		n, err := strconv.Atoi(dollars + cents)
+		return Cents(n), err
+	}, puff.Schema{Type: "string", Pattern: `^\d+\.\d{2}$`})
+
+	app := puff.App(&puff.AppConfig{Name: "decoders", DocsURL: "/docs"})
+	puff.Get(app.RootRouter, "/deliveries/{id}", func(c *puff.Context, in *DeliveryInput) {
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("%s %s %s %s %d %s",
+			in.ID, in.At.Format(time.RFC3339), in.Day.Format(time.DateOnly), in.Within, in.Tip, in.ClientIP)})
+	})
+
+	id := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	req := httptest.NewRequest(http.MethodGet, "/deliveries/"+id+"?at=2024-06-01T12:30:00Z&day=2024-06-02&within=1h30m&tip=2.50", nil)
+	req.Header.Set("X-Client-IP", "10.0.0.1")
+	w := httptest.NewRecorder()
+	app.RootRouter.ServeHTTP(w, req)
+	expected := id + " 2024-06-01T12:30:00Z 2024-06-02 1h30m0s 250 10.0.0.1"
+	if w.Body.String() != expected {
+		t.Errorf("expected %s, got %s", expected, w.Body.String())
+	}
+	for _, query := range []string{"at=yesterday", "at=2024-06-01T12:30:00Z&day=June", "at=2024-06-01T12:30:00Z&within=soon"} {
+		if w := serve(app.RootRouter, http.MethodGet, "/deliveries/"+id+"?"+query); w.Code != http.StatusBadRequest {
+			t.Errorf("?%s: expected a bad request, got %d", query, w.Code)
+		}
+	}
+	if w := serve(app.RootRouter, http.MethodGet, "/deliveries/42?at=2024-06-01T12:30:00Z"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid uuid to be a bad request, got %d", w.Code)
+	}
+
+	base := testlisten(t, app)
+	res, err := http.Get(base + "/docs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	spec := puff.OpenAPI{}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("unexpected error decoding the spec: %s", err.Error())
+	}
+	schemas := map[string]string{}
+	for _, param := range spec.Paths["/deliveries/{id}"].Get.Parameters {
+		schemas[param.Name] = param.Schema.Type + " " + param.Schema.Format
+	}
+	for name, expected := range map[string]string{
+		"ID":          "string uuid",
+		"at":          "string date-time",
+		"day":         "string date-time",
+		"within":      "string duration",
+		"tip":         "string ",
+		"X-Client-IP": "string ",
+	} {
+		if schemas[name] != expected {
+			t.Errorf("param %s: expected schema %q, got %q", name, expected, schemas[name])
+		}
+	}
+}
+
+type DeliveryBodyInput struct {
+	Body struct {
+		ID        uuid.UUID       `json:"id"`
+		At        time.Time       `json:"at"`
+		Cancelled *time.Time      `json:"cancelled" required:"false"`
+		Wait      time.Duration   `json:"wait" required:"false"`
+		Day       time.Time       `json:"day" format:"2006-01-02" required:"false"`
+		Tip       Cents           `json:"tip" required:"false"`
+		Slots     []time.Duration `json:"slots" required:"false"`
+	}
+}
+
+func TestDecodersBody(t *testing.T) {
+	puff.RegisterDecoder(func(value string, _ string) (Cents, error) {
+		dollars, cents, _ := strings.Cut(value, ".")
+		n, err := strconv.Atoi(dollars + cents)
+		return Cents(n), err
+	}, puff.Schema{Type: "string", Pattern: `^\d+\.\d{2}$`})
+
+	app := puff.App(&puff.AppConfig{Name: "decoders body"})
+	puff.Post(app.RootRouter, "/deliveries", func(c *puff.Context, in *DeliveryBodyInput) {
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("%s %s %s %s %d %v",
+			in.Body.ID, in.Body.At.Format(time.RFC3339), in.Body.Wait, in.Body.Day.Format(time.DateOnly), in.Body.Tip, in.Body.Slots)})
+	})
+
+	id := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	at := `"id": "` + id + `", "at": "2024-06-01T12:30:00Z"`
+	tests := []struct {
+		body     string
+		status   int
+		expected string
+	}{
+		{`{` + at + `}`, http.StatusOK, id + " 2024-06-01T12:30:00Z 0s 0001-01-01 0 []"},
+		{`{` + at + `, "cancelled": null}`, http.StatusOK, id + " 2024-06-01T12:30:00Z 0s 0001-01-01 0 []"},
+		// body fields are decoded with the decoders of their types, like params.
+		{`{` + at + `, "wait": "1h", "day": "2024-06-02", "tip": "2.50", "slots": ["15m", "30m"]}`, http.StatusOK, id + " 2024-06-01T12:30:00Z 1h0m0s 2024-06-02 250 [15m0s 30m0s]"},
+		{`{"id": "42", "at": "2024-06-01T12:30:00Z"}`, http.StatusBadRequest, `"field":"Body.id","pointer":"/id","code":"invalid"`},
+		{`{"id": "` + id + `", "at": "yesterday"}`, http.StatusBadRequest, `"field":"Body.at","pointer":"/at","code":"invalid"`},
+		{`{"id": "` + id + `", "at": 1717245000}`, http.StatusBadRequest, `"field":"Body.at","pointer":"/at","code":"invalid"`},
+		{`{` + at + `, "wait": "soon"}`, http.StatusBadRequest, `"field":"Body.wait","pointer":"/wait","code":"invalid"`},
+		{`{` + at + `, "day": "2024-06-02T00:00:00Z"}`, http.StatusBadRequest, `"field":"Body.day","pointer":"/day","code":"invalid"`},
+		{`{` + at + `, "slots": ["15m", "later"]}`, http.StatusBadRequest, `"field":"Body.slots[1]","pointer":"/slots/1","code":"invalid"`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/deliveries", strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		app.RootRouter.ServeHTTP(w, req)
+		if w.Code != test.status || !strings.Contains(w.Body.String(), test.expected) {
+			t.Errorf("%s: expected %d %q, got %d %q", test.body, test.status, test.expected, w.Code, w.Body.String())
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package puff
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
 
 func FieldTypeError(value string, expectedType string) error {
 	return fmt.Errorf("type error: the value %s cant be used as the expected type %s", value, expectedType)
@@ -19,3 +26,292 @@ func UnexpectedJSONKey(k string) error {
 func InvalidJSONError(v string) error {
 	return fmt.Errorf("expected json, but got invalid json")
 }
+
+// HTTPError is an error with the response it is sent as. Handlers can return or report
+// one to choose the status and body of the response. It is sent as JSON.
+//
+// Example usage:
+//
+//	return Pizza{}, &puff.HTTPError{Status: http.StatusNotFound, Code: "pizza_not_found", Message: "no such pizza"}
+type HTTPError struct {
+	// Status is the status code of the response. Defaults to 500.
+	Status int `json:"-"`
+	// Code is a machine readable code identifying the error, e.g. "pizza_not_found".
+	Code string `json:"code,omitempty"`
+	// Message is a human readable description of the error.
+	Message string `json:"error"`
+	// Details is any additional data describing the error.
+	Details any `json:"details,omitempty"`
+	// Err is the error that caused the HTTPError, if any. It is not sent.
+	Err error `json:"-"`
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Message, e.Err.Error())
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// FieldError is the error a value bound to an input that fails to bind or breaks a
+// validation rule is reported as.
+type FieldError struct {
+	// In is the kind of the param the value is bound from, e.g. "query" or "body".
+	In string `json:"location"`
+	// Field is the name of the param, followed by the path to the value within it for
+	// structs and slices, e.g. "Body.toppings[1].name".
+	Field string `json:"field"`
+	// Pointer is the JSON pointer to the value within the body, e.g. "/toppings/1/name".
+	// It is only set for body params, once the error is sent.
+	Pointer string `json:"pointer,omitempty" required:"false"`
+	// Rule is the rule the value breaks, e.g. "min" or "pattern". It is "required" for
+	// missing params and "invalid" for values that cannot be bound.
+	Rule string `json:"code"`
+	// Message describes why the value breaks the rule.
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s param %s %s", e.In, e.Field, e.Message)
+}
+
+// ValidationError is the error an input that fails to bind or validate is reported as,
+// with every error found in the request.
+//
+// It is sent as a 400 if any param is missing or cannot be bound, and as a 422 if the
+// values only break validation rules, with an RFC 9457 problem details body. See
+// ValidationProblem.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{}
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// status returns the status code the error is sent with.
+func (e *ValidationError) status() int {
+	for _, err := range e.Errors {
+		if err.Rule == "invalid" || err.Rule == "required" {
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// ValidationProblem is the RFC 9457 problem details body a ValidationError is sent as,
+// with the content type application/problem+json. It is documented as the 400 and 422
+// responses of every route with an input.
+type ValidationProblem struct {
+	// Type is a URI identifying the type of the problem.
+	Type string `json:"type"`
+	// Title is the status text of the response.
+	Title string `json:"title"`
+	// Status is the status code of the response.
+	Status int `json:"status"`
+	// Detail describes the problem.
+	Detail string `json:"detail"`
+	// Instance is the path of the request.
+	Instance string `json:"instance"`
+	// Errors are the errors found in the request.
+	Errors []FieldError `json:"errors"`
+}
+
+// problem returns the problem details body of the error, for the request on c.
+func (e *ValidationError) problem(c *Context) ValidationProblem {
+	status := e.status()
+	problem := ValidationProblem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   fmt.Sprintf("the request has %d invalid values", len(e.Errors)),
+		Instance: c.Request.URL.Path,
+		Errors:   []FieldError{},
+	}
+	if len(e.Errors) == 1 {
+		problem.Detail = "the request has 1 invalid value"
+	}
+	for _, err := range e.Errors {
+		fieldError := *err
+		if fieldError.In == "body" {
+			fieldError.Pointer = jsonPointer(fieldError.Field)
+		}
+		problem.Errors = append(problem.Errors, fieldError)
+	}
+	return problem
+}
+
+// jsonPointer returns the RFC 6901 JSON pointer to the value at field within the body
+// param it names, e.g. "/toppings/1/name" for "Body.toppings[1].name".
+func jsonPointer(field string) string {
+	i := strings.IndexAny(field, ".[")
+	if i == -1 {
+		return ""
+	}
+	var b strings.Builder
+	replacer := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, token := range strings.FieldsFunc(field[i:], func(r rune) bool { return r == '.' || r == '[' || r == ']' }) {
+		b.WriteString("/" + replacer.Replace(token))
+	}
+	return b.String()
+}
+
+// ErrorHandler responds to the error reported for a request. See PuffApp.ErrorHandler.
+type ErrorHandler func(c *Context, err error)
+
+// errorMapping maps the errors matched by match to a status code.
+type errorMapping struct {
+	match  func(err error) bool
+	status int
+}
+
+// RegisterError maps the sentinel error target to status: errors that match it with
+// errors.Is are sent with status and their message, unless they are an HTTPError.
+//
+// Example usage:
+//
+//	app.RegisterError(sql.ErrNoRows, http.StatusNotFound)
+func (a *PuffApp) RegisterError(target error, status int) {
+	a.errors = append(a.errors, errorMapping{
+		match:  func(err error) bool { return errors.Is(err, target) },
+		status: status,
+	})
+}
+
+// RegisterErrorType maps the error type T to status: errors that match it with
+// errors.As are sent with status and their message, unless they are an HTTPError.
+//
+// Example usage:
+//
+//	puff.RegisterErrorType[*ValidationError](app, http.StatusUnprocessableEntity)
+func RegisterErrorType[T error](a *PuffApp, status int) {
+	a.errors = append(a.errors, errorMapping{
+		match: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+		status: status,
+	})
+}
+
+// ResolveError returns the HTTPError err is sent as. A ValidationError is a 400 or 422
+// with its errors as Details, and an HTTPError in the chain of err is returned as is.
+// Errors matching a registered error, in the order they were registered, are sent with
+// its status and their message. Any other error is an internal server error, and its
+// message is not exposed.
+func (a *PuffApp) ResolveError(err error) *HTTPError {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		status := validationError.status()
+		return &HTTPError{Status: status, Message: http.StatusText(status), Details: validationError.Errors, Err: err}
+	}
+	var httpError *HTTPError
+	if errors.As(err, &httpError) {
+		if httpError.Status == 0 {
+			resolved := *httpError
+			resolved.Status = http.StatusInternalServerError
+			return &resolved
+		}
+		return httpError
+	}
+	if a != nil {
+		for _, mapping := range a.errors {
+			if mapping.match(err) {
+				return &HTTPError{Status: mapping.status, Message: err.Error(), Err: err}
+			}
+		}
+	}
+	return &HTTPError{
+		Status:  http.StatusInternalServerError,
+		Message: http.StatusText(http.StatusInternalServerError),
+		Err:     err,
+	}
+}
+
+// DefaultErrorHandler is the ErrorHandler used if the app does not set one. It sends
+// a ValidationError as a ValidationProblem, and any other error as the HTTPError it
+// resolves to with PuffApp.ResolveError, logging internal server errors.
+func DefaultErrorHandler(c *Context, err error) {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		problem := validationError.problem(c)
+		content, _ := json.Marshal(problem)
+		c.SendResponse(GenericResponse{
+			StatusCode:  problem.Status,
+			Content:     string(content),
+			ContentType: "application/problem+json",
+		})
+		return
+	}
+	httpError := c.puff.ResolveError(err)
+	if httpError.Status >= 500 {
+		slog.Error(
+			fmt.Sprintf("Error serving %s %s", c.Request.Method, c.Request.URL.Path),
+			slog.String("Request-ID", c.GetRequestID()),
+			slog.Any("error", err),
+		)
+	}
+	c.SendResponse(JSONResponse{StatusCode: httpError.Status, Content: httpError})
+}
+
+// Error reports err as the outcome of the request. Once the handler and every
+// middleware have returned, the error is sent by the ErrorHandler of the app, so
+// middlewares can observe it with Err after calling the next handler, and replace it by
+// calling Error again. Error(nil) clears it. If a response has already been written,
+// the error is only logged.
+func (ctx *Context) Error(err error) {
+	ctx.err = err
+}
+
+// Err returns the error reported for the request with Error, if any.
+func (ctx *Context) Err() error {
+	return ctx.err
+}
+
+// ResolvedError returns the HTTPError the error reported for the request resolves to
+// with PuffApp.ResolveError, or nil if no error was reported. Middlewares can use it
+// to learn the status of a response that has not been sent yet.
+func (ctx *Context) ResolvedError() *HTTPError {
+	if ctx.err == nil {
+		return nil
+	}
+	return ctx.puff.ResolveError(ctx.err)
+}
+
+// handleError sends the error reported for the request, if any.
+func (ctx *Context) handleError() {
+	if ctx.err == nil {
+		return
+	}
+	if ctx.statusCode != 0 {
+		slog.Error(
+			fmt.Sprintf("Error serving %s %s after the response was written", ctx.Request.Method, ctx.Request.URL.Path),
+			slog.Any("error", ctx.err),
+		)
+		return
+	}
+	handler := DefaultErrorHandler
+	if ctx.puff != nil && ctx.puff.ErrorHandler != nil {
+		handler = ctx.puff.ErrorHandler
+	}
+	handler(ctx, ctx.err)
+}
@@ -0,0 +1,150 @@
+package puff_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ThePuffProject/puff"
+	"github.com/ThePuffProject/puff/middleware"
+)
+
+var errSoldOut = errors.New("sold out")
+
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", e.limit)
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "errors"})
+	app.RegisterError(errSoldOut, http.StatusConflict)
+	puff.RegisterErrorType[*quotaError](app, http.StatusTooManyRequests)
+	fail := func(err error) func(*puff.Context) {
+		return func(c *puff.Context) { c.Error(err) }
+	}
+	app.Get("/http", nil, fail(&puff.HTTPError{Status: http.StatusNotFound, Code: "pizza_not_found", Message: "no such pizza"}))
+	app.Get("/sentinel", nil, fail(fmt.Errorf("margherita: %w", errSoldOut)))
+	app.Get("/type", nil, fail(&quotaError{limit: 3}))
+	app.Get("/internal", nil, fail(errors.New("connection refused")))
+	app.Get("/written", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: "partial"})
+		c.Error(errors.New("too late"))
+	})
+
+	tests := []struct {
+		path     string
+		status   int
+		contains string
+	}{
+		{"/http", http.StatusNotFound, `"code":"pizza_not_found","error":"no such pizza"`},
+		{"/sentinel", http.StatusConflict, `"error":"margherita: sold out"`},
+		{"/type", http.StatusTooManyRequests, `"error":"quota of 3 exceeded"`},
+		{"/internal", http.StatusInternalServerError, `"error":"Internal Server Error"`},
+		{"/written", http.StatusOK, "partial"},
+	}
+	for _, test := range tests {
+		w := serve(app.RootRouter, http.MethodGet, test.path)
+		if w.Code != test.status || !strings.Contains(w.Body.String(), test.contains) {
+			t.Errorf("GET %s: expected %d containing %s, got %d %q", test.path, test.status, test.contains, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestErrorHandlerCustom(t *testing.T) {
+	var handled []error
+	app := puff.App(&puff.AppConfig{
+		Name: "custom errors",
+		ErrorHandler: func(c *puff.Context, err error) {
+			handled = append(handled, err)
+			puff.DefaultErrorHandler(c, err)
+		},
+	})
+	// middlewares observe and replace the error once the handler returns.
+	app.Use(func(next puff.HandlerFunc) puff.HandlerFunc {
+		return func(c *puff.Context) {
+			next(c)
+			if errors.Is(c.Err(), errSoldOut) {
+				c.Error(&puff.HTTPError{Status: http.StatusGone, Message: "try another pizza", Err: c.Err()})
+			}
+		}
+	})
+	app.Use(middleware.Panic())
+	app.Get("/sold-out", nil, func(c *puff.Context) { c.Error(errSoldOut) })
+	app.Get("/panic", nil, func(c *puff.Context) { panic("oven on fire") })
+
+	if w := serve(app.RootRouter, http.MethodGet, "/sold-out"); w.Code != http.StatusGone || !strings.Contains(w.Body.String(), "try another pizza") {
+		t.Errorf("expected the error replaced by the middleware, got %d %q", w.Code, w.Body.String())
+	}
+	w := serve(app.RootRouter, http.MethodGet, "/panic")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "oven") {
+		t.Errorf("expected the panic sent as an internal server error, got %d %q", w.Code, w.Body.String())
+	}
+	if len(handled) != 2 {
+		t.Fatalf("expected 2 errors handled, got %d", len(handled))
+	}
+	var panicError *middleware.PanicError
+	if !errors.As(handled[1], &panicError) || panicError.Value != "oven on fire" {
+		t.Errorf("expected the recovered panic to be a PanicError, got %v", handled[1])
+	}
+	if panicError != nil && !strings.Contains(panicError.Stack, "goroutine") {
+		t.Errorf("expected the stack trace as text, got %q", panicError.Stack)
+	}
+}
+
+func TestErrorLogging(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	app := puff.App(&puff.AppConfig{Name: "logging"})
+	app.RegisterError(errSoldOut, http.StatusConflict)
+	app.Use(middleware.Logging())
+	app.Get("/sold-out", nil, func(c *puff.Context) { c.Error(errSoldOut) })
+
+	if w := serve(app.RootRouter, http.MethodGet, "/sold-out"); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+	if !strings.Contains(logs.String(), "409") {
+		t.Errorf("expected the status of the error to be logged, got %q", logs.String())
+	}
+}
+
+type QuantityInput struct {
+	N int `kind:"query"`
+}
+
+func TestErrorMiddlewareBinding(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "binding errors"})
+	var observed error
+	app.Use(func(next puff.HandlerFunc) puff.HandlerFunc {
+		return func(c *puff.Context) {
+			c.SetResponseHeader("Access-Control-Allow-Origin", "*")
+			next(c)
+			observed = c.Err()
+		}
+	})
+	puff.Get(app.RootRouter, "/q", func(c *puff.Context, in *QuantityInput) {
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprint(in.N)})
+	})
+
+	// inputs are bound inside the middlewares, so they see the errors.
+	w := serve(app.RootRouter, http.MethodGet, "/q?N=abc")
+	if w.Code != http.StatusBadRequest || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("expected a 400 sent through the middleware, got %d %v", w.Code, w.Header())
+	}
+	var validationError *puff.ValidationError
+	if !errors.As(observed, &validationError) {
+		t.Errorf("expected the middleware to observe the validation error, got %v", observed)
+	}
+	if w := serve(app.RootRouter, http.MethodGet, "/q?N=3"); w.Body.String() != "3" || observed != nil {
+		t.Errorf("expected the bound input, got %q and error %v", w.Body.String(), observed)
+	}
+}
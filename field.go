@@ -2,9 +2,13 @@ package puff
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -21,13 +25,14 @@ func isValidKind(specified_kind string) bool {
 		specified_kind == "cookie" ||
 		specified_kind == "body" ||
 		specified_kind == "form" ||
-		specified_kind == "file"
+		specified_kind == "file" ||
+		specified_kind == "host"
 }
 
 // TODO: i dont see this being used anywhere.
 func enforceKindTypes(specifiedKind string, t reflect.Type) error {
 	switch specifiedKind {
-	case "header", "path", "query", "cookie":
+	case "header", "path", "query", "cookie", "host":
 		switch t.Kind() {
 		case reflect.String,
 			reflect.Int,
@@ -68,92 +73,232 @@ func enforceKindTypes(specifiedKind string, t reflect.Type) error {
 func handleParam(value string, param Parameter) (string, error) {
 	ok := !(value == "")
 	if !ok && param.Required {
-		return "", fmt.Errorf("required %s param %s not provided", param.In, param.Name)
+		return "", missingParam(param)
 	}
 	return value, nil
 }
 
-// validate validates the input string against the type to ensure with options
-// from Parameter.
-func validate(input map[string]any, schemaType reflect.Type) (bool, error) {
+// jsonKey returns the key of field in a JSON object: its name tag, its json tag or its
+// name, in that order.
+func jsonKey(field reflect.StructField) string {
+	if nameTag := field.Tag.Get("name"); nameTag != "" { // name takes priority over json
+		return nameTag
+	}
+	if jsonTagName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonTagName != "" {
+		return jsonTagName
+	}
+	return field.Name
+}
+
+// validate checks that the JSON object input can be decoded into schemaType. It
+// returns an error for every key that cannot, with the path of the key within the
+// object appended to path, e.g. ".toppings[1].name".
+func validate(input map[string]any, schemaType reflect.Type, path string) []*FieldError {
 	expectedNotFoundKeys := map[string]bool{}
 	fields := map[string]reflect.StructField{}
 	for i := range schemaType.NumField() {
 		field := schemaType.Field(i)
-		name := field.Name
-		nameTag := field.Tag.Get("name")
-		jsonTag := field.Tag.Get("json")
-		s := strings.Split(jsonTag, ",")
-		jsonTagName := s[0]
-		if jsonTagName != "" {
-			name = jsonTagName
-		}
-		if nameTag != "" { // name takes priority over json
-			name = nameTag
-		}
+		name := jsonKey(field)
 		fields[name] = field
 		b, _ := resolveBool(field.Tag.Get("required"), true)
 		expectedNotFoundKeys[name] = b
 	}
-	for k, v := range input {
+	// keys are checked in order so the errors are reported in a stable order.
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	errs := []*FieldError{}
+	for _, k := range keys {
+		keyPath := path + "." + k
 		required, ok := expectedNotFoundKeys[k]
 		if !ok {
-			return false, UnexpectedJSONKey(k)
-		} else {
-			delete(expectedNotFoundKeys, k)
+			errs = append(errs, &FieldError{Field: keyPath, Rule: "invalid", Message: "is not an expected key"})
+			continue
 		}
+		delete(expectedNotFoundKeys, k)
 		f := fields[k] //cannot error
-		ft := f.Type
-		p := ft.Kind() == reflect.Pointer
-		tr := reflect.TypeOf(v)
-		if tr == nil {
-			if required && !p {
-				return false, BadFieldType(k, "nil", ft.Kind().String())
-			} else {
-				continue
+		if input[k] == nil {
+			if required && f.Type.Kind() != reflect.Pointer {
+				errs = append(errs, &FieldError{Field: keyPath, Rule: "invalid", Message: fmt.Sprintf("cannot be null for expected type %s", f.Type.String())})
 			}
+			continue
 		}
-		t := tr.Kind()
-		if ft.Kind() == reflect.Pointer {
-			ft = ft.Elem()
-			// p = true
+		errs = append(errs, validateJSONValue(input[k], f.Type, f.Tag.Get("format"), keyPath)...)
+	}
+	missing := []string{}
+	for k, required := range expectedNotFoundKeys {
+		if required {
+			missing = append(missing, k)
 		}
-		switch t {
-		case reflect.String, reflect.Bool:
-			if ft.Kind() != t {
-				return false, BadFieldType(k, t.String(), ft.Kind().String())
-			}
-		case reflect.Int:
-			if isAnyOfThese(ft.Kind(), reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64) {
-				if !(v.(int) >= 0) {
-					return false, BadFieldType(k, t.String(), ft.Kind().String())
-				}
-			} else if !isAnyOfThese(ft.Kind(), reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64) {
-				return false, BadFieldType(k, t.String(), ft.Kind().String())
-			}
-		case reflect.Float32, reflect.Float64:
-			if !isAnyOfThese(ft.Kind(), reflect.Float32, reflect.Float64) {
-				return false, BadFieldType(k, t.String(), ft.Kind().String())
+	}
+	slices.Sort(missing)
+	for _, k := range missing {
+		errs = append(errs, &FieldError{Field: path + "." + k, Rule: "required", Message: "is required"})
+	}
+	return errs
+}
+
+// validateJSONValue checks that v, a non-null value decoded from JSON at path, can be
+// decoded into a value of type ft.
+func validateJSONValue(v any, ft reflect.Type, format string, path string) []*FieldError {
+	if ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	badType := func(got string) []*FieldError {
+		return []*FieldError{{Field: path, Rule: "invalid", Message: fmt.Sprintf("%s cannot be used for expected type %s", got, ft.String())}}
+	}
+	switch v := v.(type) {
+	case string:
+		if ft.Kind() == reflect.String || ft.Kind() == reflect.Interface {
+			return nil
+		}
+		// types with a decoder, such as time.Time and uuid.UUID, are sent as strings.
+		if d, ok := decoderFor(ft); ok {
+			if _, err := d.decode(v, format); err != nil {
+				return badType(strconv.Quote(v))
 			}
-		case reflect.Array, reflect.Slice:
-			if reflect.TypeOf(v).AssignableTo(f.Type) {
-				return false, BadFieldType(k, ft.String(), reflect.TypeOf(v).String())
+			return nil
+		}
+		return badType("string")
+	case bool:
+		if ft.Kind() != reflect.Bool && ft.Kind() != reflect.Interface {
+			return badType("bool")
+		}
+	case float64:
+		// JSON numbers are decoded as float64, so whole numbers are also valid integers.
+		isInt := v == math.Trunc(v)
+		switch {
+		case isAnyOfThese(ft.Kind(), reflect.Float32, reflect.Float64, reflect.Interface):
+		case isInt && isAnyOfThese(ft.Kind(), reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64):
+		case isInt && v >= 0 && isAnyOfThese(ft.Kind(), reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64):
+		default:
+			return badType("number")
+		}
+	case []any:
+		if ft.Kind() == reflect.Interface {
+			return nil
+		}
+		if ft.Kind() != reflect.Slice && ft.Kind() != reflect.Array {
+			return badType("array")
+		}
+		errs := []*FieldError{}
+		for i, e := range v {
+			if e != nil {
+				errs = append(errs, validateJSONValue(e, ft.Elem(), format, fmt.Sprintf("%s[%d]", path, i))...)
 			}
+		}
+		return errs
+	case map[string]any:
+		switch ft.Kind() {
+		case reflect.Interface:
+		case reflect.Struct:
+			return validate(v, ft, path)
 		case reflect.Map:
-			if ft.Kind() != reflect.Struct {
-				return false, BadFieldType(k, t.String(), ft.Kind().String())
+			errs := []*FieldError{}
+			for k, e := range v {
+				if e != nil {
+					errs = append(errs, validateJSONValue(e, ft.Elem(), format, path+"."+k)...)
+				}
 			}
-			return validate(v.(map[string]any), ft)
+			return errs
 		default:
-			return false, BadFieldType(k, "unsupported type: "+t.String(), ft.Kind().String())
+			return badType("object")
 		}
 	}
-	for k, required := range expectedNotFoundKeys {
-		if required {
-			return false, ExpectedButNotFound(k)
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// decodeJSON decodes raw, a JSON value checked with validate, into v. Strings are decoded
+// into types with a decoder with their decoder, and format, like params, so types such as
+// time.Duration are decoded the same way in a body. path is the path of the value within
+// the body, for the error.
+func decodeJSON(raw json.RawMessage, v reflect.Value, format string, path string) *FieldError {
+	if string(raw) == "null" {
+		return nil
+	}
+	t := v.Type()
+	invalid := func(err error) *FieldError {
+		return &FieldError{Field: path, Rule: "invalid", Message: fmt.Sprintf("%s cannot be used for expected type %s: %s", raw, t.String(), err.Error())}
+	}
+	if t.Kind() == reflect.Pointer {
+		elem := reflect.New(t.Elem())
+		if err := decodeJSON(raw, elem.Elem(), format, path); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if d, ok := decoderFor(t); ok && raw[0] == '"' {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return invalid(err)
+		}
+		decoded, err := d.decode(value, format)
+		if err != nil {
+			return invalid(err)
 		}
+		v.Set(reflect.ValueOf(decoded))
+		return nil
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		if err := json.Unmarshal(raw, v.Addr().Interface()); err != nil {
+			return invalid(err)
+		}
+		return nil
 	}
-	return true, nil
+	switch {
+	case t.Kind() == reflect.Struct && raw[0] == '{':
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return invalid(err)
+		}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			key := jsonKey(field)
+			value, ok := object[key]
+			if !ok || !field.IsExported() {
+				continue
+			}
+			if err := decodeJSON(value, v.Field(i), field.Tag.Get("format"), path+"."+key); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && raw[0] == '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return invalid(err)
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := decodeJSON(item, slice.Index(i), format, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && raw[0] == '{':
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return invalid(err)
+		}
+		m := reflect.MakeMapWithSize(t, len(object))
+		for key, value := range object {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := decodeJSON(value, elem, format, path+"."+key); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+		v.Set(m)
+	default:
+		if err := json.Unmarshal(raw, v.Addr().Interface()); err != nil {
+			return invalid(err)
+		}
+	}
+	return nil
 }
 
 // getRequestHeaderParam gets the value of the param from the header. It may return error
@@ -178,12 +323,13 @@ func getCookieParam(c *Context, param Parameter) (string, error) {
 	return handleParam(value, param)
 }
 
-func getPathParam(index int, param Parameter, matches []string) (string, error) {
-	if len(matches) > 1+index {
-		m := matches[1+index]
-		return handleParam(m, param)
+// getPathParam gets the value of the param from the path params captured while
+// routing. Path params are bound in the order they appear in the path.
+func getPathParam(index int, param Parameter, pathParams []string) (string, error) {
+	if len(pathParams) > index {
+		return handleParam(pathParams[index], param)
 	} else {
-		return "", fmt.Errorf("required path param %s not provided", param.Name)
+		return "", missingParam(param)
 	}
 }
 
@@ -198,11 +344,115 @@ func getBodyParam(c *Context, param Parameter) (string, error) {
 	return handleParam(string(body), param)
 }
 
+// getHostParam gets the value of the param captured from the host.
+// It will return an error if it is not found AND required.
+func getHostParam(c *Context, param Parameter) (string, error) {
+	return handleParam(c.GetHostParam(param.Name), param)
+}
+
 func getFormParam(c *Context, param Parameter) (string, error) {
 	return handleParam(c.GetFormValue(param.Name), param)
 }
 
-func populateField(value string, field reflect.Value) error {
+// paramStyles are the styles supported for slice params of each kind. The first is the
+// default. See https://swagger.io/docs/specification/serialization/.
+var paramStyles = map[string][]string{
+	"query":  {"form", "spaceDelimited", "pipeDelimited"},
+	"form":   {"form", "spaceDelimited", "pipeDelimited"},
+	"cookie": {"form"},
+	"header": {"simple"},
+}
+
+// paramDelimiters are the delimiters of the values of slice params that are not exploded.
+var paramDelimiters = map[string]string{
+	"form":           ",",
+	"simple":         ",",
+	"spaceDelimited": " ",
+	"pipeDelimited":  "|",
+}
+
+// resolveStyle resolves the style and explode tags of a slice param of the kind. Like
+// in OpenAPI, explode defaults to true for the form style and to false for the others.
+func resolveStyle(kind string, style string, explode string) (string, bool, error) {
+	styles, ok := paramStyles[kind]
+	if !ok {
+		return "", false, fmt.Errorf("slices are only supported for params of kind query, header, form and cookie")
+	}
+	if style == "" {
+		style = styles[0]
+	}
+	if !slices.Contains(styles, style) {
+		return "", false, fmt.Errorf("style of a %s param must be one of %s", kind, strings.Join(styles, ", "))
+	}
+	exploded, err := resolveBool(explode, style == "form")
+	if err != nil {
+		return "", false, err
+	}
+	return style, exploded, nil
+}
+
+// getParamValues gets the values of a slice param, sent either as repeated keys when
+// the param is exploded, or as values delimited according to its style. It may return
+// an error if none are found AND the param is required.
+func getParamValues(c *Context, param Parameter) ([]string, error) {
+	var raw []string
+	switch param.In {
+	case "query":
+		raw = c.Request.URL.Query()[param.Name]
+	case "form":
+		raw = c.Request.Form[param.Name]
+	case "header":
+		raw = c.Request.Header.Values(param.Name)
+	case "cookie":
+		for _, cookie := range c.Request.Cookies() {
+			if cookie.Name == param.Name {
+				raw = append(raw, cookie.Value)
+			}
+		}
+	}
+	values := []string{}
+	for _, value := range raw {
+		// header lists are always delimited, whether or not they are also repeated.
+		if param.Explode && param.Style != "simple" {
+			if value != "" {
+				values = append(values, value)
+			}
+			continue
+		}
+		for _, v := range strings.Split(value, paramDelimiters[param.Style]) {
+			if param.In == "header" {
+				v = strings.TrimSpace(v)
+			}
+			if v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	if len(values) == 0 && param.Required {
+		return nil, missingParam(param)
+	}
+	return values, nil
+}
+
+// populateSlice populates the slice field with values, converting each to the element
+// type like populateField.
+func populateSlice(values []string, format string, field reflect.Value) error {
+	slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+	for i, value := range values {
+		if err := populateField(value, format, slice.Index(i)); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// populateField populates field with value. format is the format tag of the field,
+// passed to the decoder of its type if it has one.
+func populateField(value string, format string, field reflect.Value) error {
+	if ok, err := decodeField(value, format, field); ok {
+		return err
+	}
 	fieldType := field.Type()
 	switch fieldType.Kind() {
 	case reflect.String:
@@ -242,15 +492,13 @@ func populateField(value string, field reflect.Value) error {
 			return InvalidJSONError(value)
 		}
 
-		ok, err := validate(m, fieldType)
-		if !ok {
-			return err
+		if errs := validate(m, fieldType, ""); len(errs) > 0 {
+			return &ValidationError{Errors: errs}
 		}
 
 		newField := reflect.New(fieldType)
-		err = json.Unmarshal([]byte(value), newField.Interface())
-		if err != nil {
-			return FieldTypeError(value, fieldType.Name())
+		if err := decodeJSON(json.RawMessage(strings.TrimSpace(value)), newField.Elem(), format, ""); err != nil {
+			return &ValidationError{Errors: []*FieldError{err}}
 		}
 		field.Set(newField.Elem())
 	}
@@ -258,22 +506,39 @@ func populateField(value string, field reflect.Value) error {
 	return nil
 }
 
-func populateInputSchema(c *Context, s any, p []Parameter, matches []string) error {
+// populateInputSchema binds the params p of the request into the input s and validates
+// them. Every param is bound and validated, and the errors found are reported together
+// as a *ValidationError.
+func populateInputSchema(c *Context, s any, p []Parameter, pathParams []string) error {
 	if len(p) == 0 { //no input schema
-		return nil
+		return validateInput(c, s, p)
 	}
 	// FIXME: allow user to specify memory
 	c.Request.ParseMultipartForm(10 << 20) // leftshift to represent 10 mb
 	sve := reflect.ValueOf(s).Elem()       //will not panic because we can confirm
 	pathparamsindex := 0                   //pathparamsindex is the amount of path params already reviewed
+	errs := []*FieldError{}
 	for i, pa := range p {
+		if _, ok := paramStyles[pa.In]; ok && sve.Field(i).Kind() == reflect.Slice {
+			values, err := getParamValues(c, pa)
+			if err == nil {
+				err = populateSlice(values, pa.format, sve.Field(i))
+			}
+			if err != nil {
+				errs = append(errs, paramErrors(pa, err)...)
+				continue
+			}
+			errs = append(errs, validateValue(c, pa.In, pa.Name, pa.rules, sve.Field(i))...)
+			continue
+		}
 		var value string
 		var err error
 		switch pa.In {
 		case "header":
 			value, err = getRequestHeaderParam(c, pa)
 		case "path":
-			value, err = getPathParam(pathparamsindex, pa, matches)
+			value, err = getPathParam(pathparamsindex, pa, pathParams)
+			pathparamsindex++
 		case "query":
 			value, err = getQueryParam(c, pa)
 		case "cookie":
@@ -282,15 +547,21 @@ func populateInputSchema(c *Context, s any, p []Parameter, matches []string) err
 			value, err = getBodyParam(c, pa)
 		case "form":
 			value, err = getFormParam(c, pa)
+		case "host":
+			value, err = getHostParam(c, pa)
 		case "file":
 			// special case since we're populating to *puff.File
 			newFile := new(File)
 			file, fileHeader, err := c.GetFormFile(pa.Name)
-			if err != nil {
-				return err
+			if errors.Is(err, http.ErrMissingFile) {
+				err = missingParam(pa)
 			}
-			if fileHeader == nil {
-				return fmt.Errorf("file header is nil")
+			if err == nil && fileHeader == nil {
+				err = fmt.Errorf("file header is nil")
+			}
+			if err != nil {
+				errs = append(errs, paramErrors(pa, err)...)
+				continue
 			}
 			newFile.Name = fileHeader.Filename
 			newFile.Size = fileHeader.Size
@@ -300,15 +571,48 @@ func populateInputSchema(c *Context, s any, p []Parameter, matches []string) err
 			continue
 		}
 		if err != nil {
-			return err
+			errs = append(errs, paramErrors(pa, err)...)
+			continue
+		}
+		if value == "" && !pa.Required {
+			// params that are not provided keep their zero value, and are not validated.
+			continue
 		}
 		field := sve.Field(i) //has to be there because handleInputSchema
-		err = populateField(value, field)
+		err = populateField(value, pa.format, field)
 		if err != nil {
-			return err
+			errs = append(errs, paramErrors(pa, err)...)
+			continue
 		}
+		errs = append(errs, validateValue(c, pa.In, pa.Name, pa.rules, field)...)
 	}
-	return nil
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return validateInput(c, s, p)
+}
+
+// missingParam is the error of the required param param not being provided.
+func missingParam(param Parameter) *FieldError {
+	return &FieldError{In: param.In, Field: param.Name, Rule: "required", Message: "is required"}
+}
+
+// paramErrors returns err, an error binding the param pa, as FieldErrors. The errors
+// of a value within the param, such as a key of a JSON body, are scoped to the param.
+func paramErrors(pa Parameter, err error) []*FieldError {
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		for _, fieldError := range validationError.Errors {
+			fieldError.In = pa.In
+			fieldError.Field = pa.Name + fieldError.Field
+		}
+		return validationError.Errors
+	}
+	var fieldError *FieldError
+	if errors.As(err, &fieldError) {
+		return []*FieldError{fieldError}
+	}
+	return []*FieldError{{In: pa.In, Field: pa.Name, Rule: "invalid", Message: err.Error()}}
 }
 
 type typeInfo struct {
@@ -375,7 +679,7 @@ var supportedTypes = map[string]typeInfo{
 	"uint64": newTypeInfo("integer", Schema{
 		Format:  "int64",
 		Example: "0",
-		Minimum: strconv.Itoa(2 ^ 64 - 1),
+		Minimum: "0",
 	}),
 	"float32": newTypeInfo("number", Schema{
 		Format:  "float",
@@ -401,6 +705,9 @@ func newDefinition(route *Route, schema any) Schema {
 		st = st.Elem()
 		sv = sv.Elem()
 	}
+	if d, ok := decoderFor(st); ok {
+		return d.schema
+	}
 
 	// FIXME: refactor this it could look better
 	if st.Kind() != reflect.Struct && st.Kind() != reflect.Slice && st.Kind() != reflect.Map && st.Kind() != reflect.Array && st.Kind() != reflect.Pointer {
@@ -441,6 +748,11 @@ func newDefinition(route *Route, schema any) Schema {
 		newDef.Type = "object"
 		field := st.Field(i)
 		nd := newDefinition(route, sv.Field(i).Interface())
+		fieldRules, err := parseRules(field)
+		if err != nil {
+			panic(err)
+		}
+		fieldRules.document(&nd)
 
 		fieldName := field.Name
 		fieldNameSplit := strings.Split(field.Tag.Get("json"), ",")
@@ -0,0 +1,130 @@
+package puff
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Handle registers a route on router for method and path whose handler receives the
+// request input bound into a new In for every request. The fields of In describe the
+// input exactly like the fields of a struct passed to Router.Get, with the same tags.
+// In may be struct{} for routes without input.
+//
+// Example usage:
+//
+//	type PizzaInput struct {
+//		ID int `kind:"path"`
+//	}
+//
+//	puff.Handle(router, http.MethodGet, "/pizza/{id:int}", func(c *puff.Context, in *PizzaInput) {
+//		c.SendResponse(puff.JSONResponse{Content: pizzas[in.ID]})
+//	})
+func Handle[In any](router *Router, method string, path string, handler func(c *Context, in *In)) *Route {
+	route := router.registerRoute(method, path, func(c *Context) {
+		in := Input[In](c)
+		if in == nil {
+			// the route was served before being compiled, so nothing was bound.
+			in = new(In)
+		}
+		handler(c, in)
+	}, new(In))
+	route.typed = true
+	return route
+}
+
+// Input returns the input bound for the request, or nil if the route has no input of
+// type In. Handlers of routes registered with a method of Router read their input with
+// Input, since the Fields of the route are shared by every request.
+//
+// Example usage:
+//
+//	router.Get("/pizza/{id:int}", &PizzaInput{}, func(c *puff.Context) {
+//		in := puff.Input[PizzaInput](c)
+//		c.SendResponse(puff.JSONResponse{Content: pizzas[in.ID]})
+//	})
+func Input[In any](c *Context) *In {
+	in, _ := c.input.(*In)
+	return in
+}
+
+// Get registers an HTTP GET route on router whose handler receives the bound request
+// input. See Handle.
+func Get[In any](router *Router, path string, handler func(c *Context, in *In)) *Route {
+	return Handle(router, http.MethodGet, path, handler)
+}
+
+// Post registers an HTTP POST route on router whose handler receives the bound request
+// input. See Handle.
+func Post[In any](router *Router, path string, handler func(c *Context, in *In)) *Route {
+	return Handle(router, http.MethodPost, path, handler)
+}
+
+// Put registers an HTTP PUT route on router whose handler receives the bound request
+// input. See Handle.
+func Put[In any](router *Router, path string, handler func(c *Context, in *In)) *Route {
+	return Handle(router, http.MethodPut, path, handler)
+}
+
+// Patch registers an HTTP PATCH route on router whose handler receives the bound request
+// input. See Handle.
+func Patch[In any](router *Router, path string, handler func(c *Context, in *In)) *Route {
+	return Handle(router, http.MethodPatch, path, handler)
+}
+
+// Delete registers an HTTP DELETE route on router whose handler receives the bound
+// request input. See Handle.
+func Delete[In any](router *Router, path string, handler func(c *Context, in *In)) *Route {
+	return Handle(router, http.MethodDelete, path, handler)
+}
+
+// Endpoint registers a route on router for method and path whose handler receives the
+// bound request input, like Handle, and returns its output instead of sending a response.
+//
+// The output is sent as a JSON response with status 200, and Out is documented as the
+// schema of the 200 response, so the documentation cannot drift from the handler. If Out
+// is a Response, such as JSONResponse or RedirectResponse, it is sent as is instead and
+// is not documented. A nil pointer output is sent as 204 No Content.
+//
+// A non-nil error is reported with Context.Error instead, and sent by the ErrorHandler
+// of the app.
+//
+// Example usage:
+//
+//	type Pizza struct {
+//		Name string `json:"name"`
+//	}
+//
+//	puff.Endpoint(router, http.MethodGet, "/pizza/{id:int}", func(c *puff.Context, in *PizzaInput) (Pizza, error) {
+//		return pizzas.Get(in.ID)
+//	})
+func Endpoint[In any, Out any](router *Router, method string, path string, handler func(c *Context, in *In) (Out, error)) *Route {
+	route := Handle(router, method, path, func(c *Context, in *In) {
+		out, err := handler(c, in)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		sendOutput(c, out)
+	})
+	outType := reflect.TypeFor[Out]()
+	if !outType.Implements(reflect.TypeFor[Response]()) {
+		if outType.Kind() == reflect.Pointer {
+			outType = outType.Elem()
+		}
+		route.Responses[http.StatusOK] = func() reflect.Type { return outType }
+	}
+	return route
+}
+
+// sendOutput sends the output of an Endpoint handler.
+func sendOutput(c *Context, out any) {
+	if res, ok := out.(Response); ok {
+		c.SendResponse(res)
+		return
+	}
+	if v := reflect.ValueOf(out); v.Kind() == reflect.Pointer && v.IsNil() {
+		c.SetStatusCode(http.StatusNoContent)
+		return
+	}
+	c.SendResponse(JSONResponse{StatusCode: http.StatusOK, Content: out})
+}
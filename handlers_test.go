@@ -0,0 +1,237 @@
+package puff_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThePuffProject/puff"
+)
+
+type OrderInput struct {
+	Store int    `kind:"path"`
+	Name  string `kind:"query" name:"name"`
+	Note  string `kind:"query" name:"note" required:"false"`
+}
+
+func TestTypedHandlers(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "typed"})
+	puff.Get(app.RootRouter, "/stores/{store:int}/orders", func(c *puff.Context, in *OrderInput) {
+		// give concurrent requests a chance to interleave.
+		time.Sleep(time.Millisecond)
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("%d %s %q", in.Store, in.Name, in.Note)})
+	})
+	puff.Post(app.RootRouter, "/ping", func(c *puff.Context, in *struct{}) {
+		c.SendResponse(puff.GenericResponse{Content: "pong"})
+	})
+
+	// each request is bound into its own input.
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := fmt.Sprintf("/stores/%d/orders?name=order-%d", i, i)
+			if i%2 == 0 {
+				path += "&note=extra"
+			}
+			expected := fmt.Sprintf("%d order-%d %q", i, i, "")
+			if i%2 == 0 {
+				expected = fmt.Sprintf("%d order-%d %q", i, i, "extra")
+			}
+			if w := serve(app.RootRouter, http.MethodGet, path); w.Body.String() != expected {
+				t.Errorf("GET %s: expected %q, got %q", path, expected, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if w := serve(app.RootRouter, http.MethodGet, "/stores/1/orders"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a missing required query param to be a bad request, got %d", w.Code)
+	}
+	if w := serve(app.RootRouter, http.MethodPost, "/ping"); w.Body.String() != "pong" {
+		t.Errorf("expected a typed route without input, got %q", w.Body.String())
+	}
+}
+
+func TestLegacyFields(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "legacy"})
+	shared := OrderInput{}
+	app.Get("/stores/{store:int}/orders", &shared, func(c *puff.Context) {
+		in := puff.Input[OrderInput](c)
+		// give concurrent requests a chance to interleave.
+		time.Sleep(time.Millisecond)
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("%d %s %q", in.Store, in.Name, in.Note)})
+	})
+
+	// each request reads its own input with Input, and the shared struct is never written.
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := fmt.Sprintf("/stores/%d/orders?name=order-%d", i, i)
+			expected := fmt.Sprintf("%d order-%d %q", i, i, "")
+			if w := serve(app.RootRouter, http.MethodGet, path); w.Body.String() != expected {
+				t.Errorf("GET %s: expected %q, got %q", path, expected, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+	if shared != (OrderInput{}) {
+		t.Errorf("expected the shared struct to be left alone, got %+v", shared)
+	}
+
+	// handlers not migrated yet can opt in to the copy.
+	copying := puff.App(&puff.AppConfig{Name: "legacy", EnableFieldsCopy: true})
+	input := OrderInput{}
+	copying.Get("/stores/{store:int}/orders", &input, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("%d %s %q", input.Store, input.Name, input.Note)})
+	})
+	if w := serve(copying.RootRouter, http.MethodGet, "/stores/3/orders?name=margherita&note=extra"); w.Body.String() != `3 margherita "extra"` {
+		t.Errorf("expected the input in the shared struct, got %q", w.Body.String())
+	}
+	// values from earlier requests do not leak into later ones.
+	if w := serve(copying.RootRouter, http.MethodGet, "/stores/4/orders?name=marinara"); w.Body.String() != `4 marinara ""` {
+		t.Errorf("expected a fresh input, got %q", w.Body.String())
+	}
+}
+
+type Order struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type OrderIDInput struct {
+	ID int `kind:"path"`
+}
+
+func TestEndpoints(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "endpoints", DocsURL: "/docs"})
+	puff.Endpoint(app.RootRouter, http.MethodGet, "/orders/{id:int}", func(c *puff.Context, in *OrderIDInput) (Order, error) {
+		if in.ID == 0 {
+			return Order{}, errors.New("order 0 is corrupt")
+		}
+		return Order{ID: in.ID, Name: "margherita"}, nil
+	})
+	puff.Endpoint(app.RootRouter, http.MethodDelete, "/orders/{id:int}", func(c *puff.Context, in *OrderIDInput) (*Order, error) {
+		return nil, nil
+	})
+	puff.Endpoint(app.RootRouter, http.MethodGet, "/menu", func(c *puff.Context, in *struct{}) (puff.Response, error) {
+		return puff.GenericResponse{Content: "menu"}, nil
+	})
+
+	w := serve(app.RootRouter, http.MethodGet, "/orders/3")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"id":3,"name":"margherita"}` {
+		t.Errorf("expected the output encoded as JSON, got %d %q", w.Code, w.Body.String())
+	}
+	w = serve(app.RootRouter, http.MethodGet, "/orders/0")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "corrupt") {
+		t.Errorf("expected a 500 not leaking the error, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(app.RootRouter, http.MethodDelete, "/orders/3"); w.Code != http.StatusNoContent {
+		t.Errorf("expected a nil output to be no content, got %d", w.Code)
+	}
+	if w := serve(app.RootRouter, http.MethodGet, "/menu"); w.Body.String() != "menu" {
+		t.Errorf("expected a Response output to be sent as is, got %q", w.Body.String())
+	}
+
+	base := testlisten(t, app)
+	res, err := http.Get(base + "/docs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	spec := puff.OpenAPI{}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("unexpected error decoding the spec: %s", err.Error())
+	}
+	response, ok := spec.Paths["/orders/{id}"].Get.Responses["200"]
+	if !ok {
+		t.Fatalf("expected the 200 response to be documented")
+	}
+	if ref := response.Content["application/json"].Schema.Ref; ref != "#/components/schemas/Order" {
+		t.Errorf("expected the 200 schema to be inferred from the output, got %q", ref)
+	}
+	if _, ok := spec.Paths["/menu"].Get.Responses["200"]; ok {
+		t.Errorf("expected a Response output not to be documented")
+	}
+}
+
+type SearchInput struct {
+	Tags     []string `kind:"query" name:"tag" required:"false"`
+	IDs      []int    `kind:"query" name:"ids" explode:"false" required:"false"`
+	Toppings []string `kind:"query" name:"toppings" style:"pipeDelimited" required:"false"`
+	Accept   []string `kind:"header" name:"X-Accept-Crust" required:"false"`
+	Sizes    []int    `kind:"form" name:"size" required:"false"`
+}
+
+func TestSliceBinding(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "slices", DocsURL: "/docs"})
+	puff.Get(app.RootRouter, "/search", func(c *puff.Context, in *SearchInput) {
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("%q %v %q %q %v", in.Tags, in.IDs, in.Toppings, in.Accept, in.Sizes)})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?tag=veggie&tag=spicy&ids=1,2,3&toppings=basil|olive&size=10&size=12", nil)
+	req.Header.Add("X-Accept-Crust", "thin, thick")
+	req.Header.Add("X-Accept-Crust", "stuffed")
+	w := httptest.NewRecorder()
+	app.RootRouter.ServeHTTP(w, req)
+	expected := `["veggie" "spicy"] [1 2 3] ["basil" "olive"] ["thin" "thick" "stuffed"] [10 12]`
+	if w.Body.String() != expected {
+		t.Errorf("expected %s, got %s", expected, w.Body.String())
+	}
+	if w := serve(app.RootRouter, http.MethodGet, "/search"); w.Body.String() != `[] [] [] [] []` {
+		t.Errorf("expected empty slices, got %s", w.Body.String())
+	}
+	if w := serve(app.RootRouter, http.MethodGet, "/search?ids=1,two"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid element to be a bad request, got %d", w.Code)
+	}
+
+	base := testlisten(t, app)
+	res, err := http.Get(base + "/docs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	spec := puff.OpenAPI{}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("unexpected error decoding the spec: %s", err.Error())
+	}
+	styles := map[string]string{}
+	for _, param := range spec.Paths["/search"].Get.Parameters {
+		styles[param.Name] = fmt.Sprintf("%s %t", param.Style, param.Explode)
+	}
+	for name, expected := range map[string]string{
+		"tag":            "form true",
+		"ids":            "form false",
+		"toppings":       "pipeDelimited false",
+		"X-Accept-Crust": "simple false",
+		"size":           "form true",
+	} {
+		if styles[name] != expected {
+			t.Errorf("param %s: expected style and explode %s, got %s", name, expected, styles[name])
+		}
+	}
+}
+
+func TestSliceBindingInvalidStyle(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "invalid slices"})
+	puff.Get(app.RootRouter, "/search", func(c *puff.Context, in *struct {
+		Tags []string `kind:"header" style:"pipeDelimited"`
+	}) {
+	})
+	err := app.ListenAndServe("127.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "style of a header param must be one of simple") {
+		t.Errorf("expected an invalid style error, got %v", err)
+	}
+}
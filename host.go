@@ -0,0 +1,134 @@
+package puff
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostPattern matches the host of a request against a pattern such as "api.example.com"
+// or "{tenant}.example.com". Each label of the pattern is either static, matched
+// case-insensitively, or a param capturing exactly one label. Params support the same
+// constraints as path params, e.g. "{tenant:alpha}.example.com".
+type hostPattern struct {
+	// Pattern is the pattern as written on the Router.
+	Pattern string
+	labels  []hostLabel
+}
+
+type hostLabel struct {
+	static  string
+	param   pathParam
+	isParam bool
+}
+
+// parseHostPattern parses a Router.Host pattern.
+func parseHostPattern(pattern string) (*hostPattern, error) {
+	hp := &hostPattern{Pattern: pattern}
+	for _, label := range strings.Split(pattern, ".") {
+		p, isParam, err := parsePathParam(label)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host pattern %s: %s", pattern, err.Error())
+		}
+		if p.CatchAll {
+			return nil, fmt.Errorf("invalid host pattern %s: catch-all params are not supported in hosts", pattern)
+		}
+		if !isParam {
+			if label == "" {
+				return nil, fmt.Errorf("invalid host pattern %s: empty label", pattern)
+			}
+			hp.labels = append(hp.labels, hostLabel{static: label})
+			continue
+		}
+		hp.labels = append(hp.labels, hostLabel{param: p, isParam: true})
+	}
+	return hp, nil
+}
+
+// match reports whether host satisfies the pattern and returns the captured params.
+// The port, if any, is ignored.
+func (hp *hostPattern) match(host string) (map[string]string, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	labels := strings.Split(host, ".")
+	if len(labels) != len(hp.labels) {
+		return nil, false
+	}
+	var captures map[string]string
+	for i, label := range hp.labels {
+		if !label.isParam {
+			if !strings.EqualFold(label.static, labels[i]) {
+				return nil, false
+			}
+			continue
+		}
+		p := label.param
+		if !strings.HasPrefix(labels[i], p.Prefix) || !strings.HasSuffix(labels[i], p.Suffix) ||
+			len(labels[i]) < len(p.Prefix)+len(p.Suffix) {
+			return nil, false
+		}
+		value := labels[i][len(p.Prefix) : len(labels[i])-len(p.Suffix)]
+		if !p.check(value) {
+			return nil, false
+		}
+		if captures == nil {
+			captures = make(map[string]string)
+		}
+		captures[p.Name] = value
+	}
+	return captures, true
+}
+
+// params returns the number of params in the pattern. Patterns with fewer params are
+// more specific and are matched first.
+func (hp *hostPattern) params() int {
+	n := 0
+	for _, label := range hp.labels {
+		if label.isParam {
+			n++
+		}
+	}
+	return n
+}
+
+// server returns the OpenAPI server describing the pattern.
+func (hp *hostPattern) server(scheme string) Server {
+	labels := []string{}
+	variables := map[string]ServerVariable{}
+	for _, label := range hp.labels {
+		if !label.isParam {
+			labels = append(labels, label.static)
+			continue
+		}
+		p := label.param
+		labels = append(labels, p.Prefix+"{"+p.Name+"}"+p.Suffix)
+		description := ""
+		if p.Constraint != "" {
+			description = "must satisfy " + p.Constraint
+		}
+		variables[p.Name] = ServerVariable{Default: p.Name, Description: description}
+	}
+	return Server{
+		URL:       scheme + "://" + strings.Join(labels, "."),
+		Variables: variables,
+	}
+}
+
+// hostTree is the routing tree for the routes served on a host pattern.
+type hostTree struct {
+	pattern *hostPattern
+	tree    *node
+}
+
+// resolveHost returns the Host pattern of the closest router, or "" if the route is
+// served on every host.
+func (route *Route) resolveHost() string {
+	for current := route.Router; current != nil; current = current.parent {
+		if current.Host != "" {
+			return current.Host
+		}
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package puff
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a route registered on an app, as returned by PuffApp.Routes.
+type RouteInfo struct {
+	// Methods are the HTTP methods the route is served for.
+	Methods []string
+	// Path is the full path of the route, including the prefixes of its routers.
+	Path string
+	// Name is the name given with WithName, if any.
+	Name string
+	// Params are the names of the params in Path, in order.
+	Params []string
+	// Router is the name of the router the route belongs to.
+	Router string
+	// Middlewares are the names of the functions of the middlewares applied to the
+	// route, outermost first.
+	Middlewares []string
+	// File and Line are where the route was registered.
+	File string
+	Line int
+}
+
+// Routes returns a description of every route registered on the app, in the order
+// they were registered, router by router.
+func (a *PuffApp) Routes() []RouteInfo {
+	infos := []RouteInfo{}
+	a.RootRouter.walk(func(r *Router) {
+		for _, route := range r.Routes {
+			infos = append(infos, route.info(r))
+		}
+	})
+	return infos
+}
+
+// RouteTable returns the routes of the app formatted as a table, one route per line.
+// It is logged at startup when the logger is at the debug level.
+func (a *PuffApp) RouteTable() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tNAME\tROUTER\tMIDDLEWARES\tSOURCE")
+	for _, info := range a.Routes() {
+		source := "-"
+		if info.File != "" {
+			source = fmt.Sprintf("%s:%d", filepath.Base(info.File), info.Line)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			strings.Join(info.Methods, ","),
+			info.Path,
+			orDash(info.Name),
+			info.Router,
+			orDash(strings.Join(info.Middlewares, ", ")),
+			source,
+		)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// DOT returns the router tree, with the routes of each router, as a Graphviz graph.
+//
+// Example usage:
+//
+//	os.WriteFile("routes.dot", []byte(app.RootRouter.DOT()), 0644)
+//	// dot -Tpng routes.dot -o routes.png
+func (r *Router) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph puff {\n\trankdir=LR;\n\tnode [fontname=\"Helvetica\"];\n")
+	r.graph(func(id string, label string, isRouter bool) {
+		shape := "box"
+		if !isRouter {
+			shape = "note"
+		}
+		fmt.Fprintf(&b, "\t%s [shape=%s, label=%s];\n", id, shape, dotQuote(label))
+	}, func(from string, to string) {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", from, to)
+	})
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid returns the router tree, with the routes of each router, as a Mermaid
+// flowchart that can be embedded in Markdown.
+func (r *Router) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	r.graph(func(id string, label string, isRouter bool) {
+		label = strings.ReplaceAll(label, `"`, "#quot;")
+		label = strings.ReplaceAll(label, "\n", "<br/>")
+		if isRouter {
+			fmt.Fprintf(&b, "\t%s[\"%s\"]\n", id, label)
+			return
+		}
+		fmt.Fprintf(&b, "\t%s([\"%s\"])\n", id, label)
+	}, func(from string, to string) {
+		fmt.Fprintf(&b, "\t%s --> %s\n", from, to)
+	})
+	return b.String()
+}
+
+// graph visits the router tree depth first, reporting a node for every router and
+// route and an edge from every router to its routes and sub-routers.
+func (r *Router) graph(node func(id string, label string, isRouter bool), edge func(from string, to string)) {
+	routers, routes := 0, 0
+	var visit func(router *Router) string
+	visit = func(router *Router) string {
+		id := fmt.Sprintf("router%d", routers)
+		routers++
+		label := router.Name
+		if prefix := router.fullPrefix(); prefix != "" {
+			label += "\n" + prefix
+		}
+		if router.Host != "" {
+			label += "\nhost " + router.Host
+		}
+		node(id, label, true)
+		for _, route := range router.Routes {
+			routeID := fmt.Sprintf("route%d", routes)
+			routes++
+			label := strings.Join(route.Methods(), ",") + " " + route.completePath()
+			if route.Name != "" {
+				label += "\n" + route.Name
+			}
+			node(routeID, label, false)
+			edge(id, routeID)
+		}
+		for _, sub := range router.Routers {
+			edge(id, visit(sub))
+		}
+		return id
+	}
+	visit(r)
+}
+
+// walk calls fn for the router and every router below it, depth first.
+func (r *Router) walk(fn func(*Router)) {
+	fn(r)
+	for _, sub := range r.Routers {
+		sub.walk(fn)
+	}
+}
+
+// info describes the route. router is the router the route is registered on, which
+// is set on the route itself only once it has been compiled.
+func (route *Route) info(router *Router) RouteInfo {
+	info := RouteInfo{
+		Methods: route.Methods(),
+		Name:    route.Name,
+		Router:  router.Name,
+		Params:  []string{},
+		File:    route.file,
+		Line:    route.line,
+	}
+	info.Path = router.fullPrefix() + route.Path
+	if params, err := parsePathParams(info.Path); err == nil {
+		for _, p := range params {
+			info.Params = append(info.Params, p.Name)
+		}
+	}
+	var middlewares []*Middleware
+	for current := router; current != nil; current = current.parent {
+		middlewares = append(slices.Clone(current.Middlewares), middlewares...)
+	}
+	middlewares = append(middlewares, route.Middlewares...)
+	info.Middlewares = []string{}
+	for _, m := range middlewares {
+		info.Middlewares = append(info.Middlewares, funcName(*m))
+	}
+	return info
+}
+
+// funcName returns the name of fn without its package path, e.g.
+// "middleware.createCORSMiddleware.func1".
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	name := f.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// dotQuote quotes s as a Graphviz string, keeping line breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -24,6 +24,10 @@ var DefaultLoggingConfig LoggingConfig = LoggingConfig{
 	LoggingFunction: func(ctx puff.Context, startTime time.Time) {
 		processingTime := time.Since(startTime).String()
 		sc := ctx.GetStatusCode()
+		if httpError := ctx.ResolvedError(); sc == 0 && httpError != nil {
+			// the error is sent once every middleware has returned.
+			sc = httpError.Status
+		}
 		var statusColor string
 		switch {
 		case sc >= 500:
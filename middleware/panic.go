@@ -2,8 +2,7 @@ package middleware
 
 import (
 	"fmt"
-	"log/slog"
-	"net/http"
+	"runtime/debug"
 
 	"github.com/ThePuffProject/puff"
 )
@@ -15,21 +14,35 @@ type PanicConfig struct {
 	Skip func(*puff.Context) bool
 	// FormatErrorResponse provides a function that recieves the context of the route that resulted in a panic and the error.
 	// It should provide a response that can be sent back to the user.
+	// If nil, the panic is reported with Context.Error as a *PanicError and sent by the ErrorHandler of the app.
 	FormatErrorResponse func(c puff.Context, err any) puff.Response
 }
 
-// DefaultCSRFMiddleware is a PanicConfig with specified default values.
+// DefaultPanicConfig is a PanicConfig with specified default values.
 var DefaultPanicConfig PanicConfig = PanicConfig{
-	FormatErrorResponse: func(c puff.Context, err any) puff.Response {
-		errorID := puff.RandomNanoID()
-		slog.Error("Panic During Execution", slog.String("ERROR ID", errorID), slog.Any("Error", err))
-		errorMsg := fmt.Sprintf("There was a panic during the execution recovered by the panic handling middleware. Error ID: " + errorID)
-		return puff.JSONResponse{StatusCode: http.StatusInternalServerError, Content: map[string]any{"error": errorMsg, "Request-ID": c.GetRequestID()}}
-	},
 	Skip: DefaultSkipper,
 }
 
-// createCSRFMiddleware is used to create a panic middleware with a config.
+// PanicError is the error a panic recovered by the Panic middleware is reported as.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked. It is a string so that it is
+	// logged as text.
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// createPanicMiddleware is used to create a panic middleware with a config.
 func createPanicMiddleware(pc PanicConfig) puff.Middleware {
 	return func(next puff.HandlerFunc) puff.HandlerFunc {
 		return func(c *puff.Context) {
@@ -39,10 +52,14 @@ func createPanicMiddleware(pc PanicConfig) puff.Middleware {
 			}
 			defer func() {
 				a := recover()
-				if a != nil {
-					res := pc.FormatErrorResponse(*c, a)
-					c.SendResponse(res)
+				if a == nil {
+					return
+				}
+				if pc.FormatErrorResponse != nil {
+					c.SendResponse(pc.FormatErrorResponse(*c, a))
+					return
 				}
+				c.Error(&PanicError{Value: a, Stack: string(debug.Stack())})
 			}()
 			next(c)
 		}
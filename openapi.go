@@ -2,6 +2,7 @@ package puff
 
 import (
 	_ "embed"
+	"encoding/json"
 	"net/http"
 	"reflect"
 	"slices"
@@ -158,6 +159,10 @@ type Parameter struct {
 	Explode         bool   `json:"explode"`
 	AllowReserved   bool   `json:"allowReserved"`
 	Schema          Schema `json:"schema"`
+	// format is the format tag of the field the param is bound to.
+	format string
+	// rules are the validation rules of the field the param is bound to.
+	rules rules
 }
 
 // RequestBodyOrReference is a union type representing either a Request Body Object or a Reference Object.
@@ -182,7 +187,13 @@ type Schema struct {
 	// This can be expanded based on the needs of your application.
 	Type                 string             `json:"type,omitempty"`
 	Format               string             `json:"format,omitempty"`
-	Minimum              string             `json:"minimum,omitempty"`
+	Minimum              json.Number        `json:"minimum,omitempty"`
+	Maximum              json.Number        `json:"maximum,omitempty"`
+	MultipleOf           json.Number        `json:"multipleOf,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
 	Items                *Schema            `json:"items,omitempty"`
 	Ref                  string             `json:"$ref,omitempty"`
 	Properties           map[string]*Schema `json:"properties,omitempty"`
@@ -250,6 +261,15 @@ type SwaggerUIConfig struct {
 	RequestDuration bool
 	// FaviconURL is the location of favicon image to display
 	FaviconURL string
+	// URLs lists several OpenAPI JSON documents, such as one per API version, to choose
+	// from. If set, it is used instead of URL.
+	URLs []SwaggerUIURL
+}
+
+// SwaggerUIURL is an OpenAPI JSON document listed in SwaggerUIConfig.URLs.
+type SwaggerUIURL struct {
+	URL  string
+	Name string
 }
 
 func parameterToRequestBodyOrReference(p Parameter) RequestBodyOrReference {
@@ -278,7 +298,7 @@ func addRoute(route *Route, tags *[]Tag, tagNames *[]string, paths *Paths) *Path
 	}
 	if !slices.Contains(*tagNames, tag) {
 		*tagNames = append(*tagNames, tag)
-		*tags = append(*tags, Tag{Name: tag, Description: ""})
+		*tags = append(*tags, Tag{Name: tag, Description: route.Router.Description})
 	}
 
 	description := route.Description
@@ -293,6 +313,10 @@ func addRoute(route *Route, tags *[]Tag, tagNames *[]string, paths *Paths) *Path
 			requestBody = parameterToRequestBodyOrReference(p)
 			continue
 		}
+		if p.In == "host" {
+			// host params are described by the server variables of the operation.
+			continue
+		}
 		if p.In == "file" {
 			requestBody = RequestBodyOrReference{
 				Content: map[string]MediaType{
@@ -318,6 +342,8 @@ func addRoute(route *Route, tags *[]Tag, tagNames *[]string, paths *Paths) *Path
 			Required:    p.Required,
 			In:          p.In,
 			Deprecated:  p.Deprecated,
+			Style:       p.Style,
+			Explode:     p.Explode,
 		}
 		np.Schema = p.Schema
 		parameters = append(parameters, np)
@@ -331,22 +357,35 @@ func addRoute(route *Route, tags *[]Tag, tagNames *[]string, paths *Paths) *Path
 		Responses:   convertRouteResponsestoOpenAPIResponses(*route),
 		Description: description, // TODO: needs to be dynamic on route
 	}
+	if host := route.resolveHost(); host != "" {
+		if pattern, err := parseHostPattern(host); err == nil {
+			pathMethod.Servers = []Server{pattern.server(route.Router.puff.scheme())}
+		}
+	}
 
-	pathItem := (*paths)[route.fullPath]
-	switch route.Protocol {
-	// TODO: handle other protocols
-	case http.MethodGet:
-		pathItem.Get = pathMethod
-	case http.MethodPost:
-		pathItem.Post = pathMethod
-	case http.MethodPut:
-		pathItem.Put = pathMethod
-	case http.MethodPatch:
-		pathItem.Patch = pathMethod
-	case http.MethodDelete:
-		pathItem.Delete = pathMethod
+	path := openAPIPath(route.fullPath)
+	pathItem := (*paths)[path]
+	for _, method := range route.Methods() {
+		switch method {
+		case http.MethodGet:
+			pathItem.Get = pathMethod
+		case http.MethodPost:
+			pathItem.Post = pathMethod
+		case http.MethodPut:
+			pathItem.Put = pathMethod
+		case http.MethodPatch:
+			pathItem.Patch = pathMethod
+		case http.MethodDelete:
+			pathItem.Delete = pathMethod
+		case http.MethodHead:
+			pathItem.Head = pathMethod
+		case http.MethodOptions:
+			pathItem.Options = pathMethod
+		case http.MethodTrace:
+			pathItem.Trace = pathMethod
+		}
 	}
-	(*paths)[route.fullPath] = pathItem
+	(*paths)[path] = pathItem
 
 	return paths
 }
@@ -366,5 +405,20 @@ func convertRouteResponsestoOpenAPIResponses(route Route) map[string]OpenAPIResp
 			},
 		}
 	}
+	// inputs that fail to bind or validate are sent as a ValidationProblem.
+	if len(route.params) > 0 {
+		for _, statusCode := range []int{http.StatusBadRequest, http.StatusUnprocessableEntity} {
+			sc := strconv.Itoa(statusCode)
+			if _, ok := openAPIResponses[sc]; ok {
+				continue
+			}
+			openAPIResponses[sc] = OpenAPIResponse{
+				Description: http.StatusText(statusCode),
+				Content: map[string]MediaType{
+					"application/problem+json": {Schema: newDefinition(&route, new(ValidationProblem))},
+				},
+			}
+		}
+	}
 	return openAPIResponses
 }
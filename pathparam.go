@@ -0,0 +1,257 @@
+package puff
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pathParam describes a param declared in a route path. The supported forms are:
+//
+//	{name}            captures any non-empty segment
+//	{name:int}        captures a segment satisfying a built-in constraint
+//	{name:[a-z-]+}    captures a segment matching a regular expression
+//	{name...}         captures the rest of the path, slashes included
+//
+// Params may be surrounded by literal text within their segment, e.g. "{name}.json",
+// except for catch-all params which must be the whole, and last, segment.
+type pathParam struct {
+	// Name is the name between the braces.
+	Name string
+	// Prefix and Suffix are the literal parts of the segment around the param.
+	Prefix string
+	Suffix string
+	// Constraint is the text after the colon, e.g. "int" or "[a-z-]+".
+	Constraint string
+	// CatchAll is true for {name...} params.
+	CatchAll bool
+
+	check  func(string) bool
+	schema Schema
+}
+
+// paramConstraint is a built-in constraint usable as {name:constraint}.
+type paramConstraint struct {
+	check  func(string) bool
+	schema Schema
+}
+
+var builtinConstraints = map[string]paramConstraint{
+	"int": {
+		check: func(s string) bool {
+			_, err := strconv.ParseInt(s, 10, 64)
+			return err == nil
+		},
+		schema: Schema{Type: "integer", Format: "int64"},
+	},
+	"uint": {
+		check: func(s string) bool {
+			_, err := strconv.ParseUint(s, 10, 64)
+			return err == nil
+		},
+		schema: Schema{Type: "integer", Format: "int64", Minimum: "0"},
+	},
+	"float": {
+		check: func(s string) bool {
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		},
+		schema: Schema{Type: "number", Format: "double"},
+	},
+	"bool": {
+		check: func(s string) bool {
+			_, err := strconv.ParseBool(s)
+			return err == nil
+		},
+		schema: Schema{Type: "boolean"},
+	},
+	"uuid": {
+		check:  isUUID,
+		schema: Schema{Type: "string", Format: "uuid"},
+	},
+	"alpha": {
+		check: func(s string) bool {
+			return strings.IndexFunc(s, func(r rune) bool {
+				return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+			}) == -1
+		},
+		schema: Schema{Type: "string", Pattern: "^[a-zA-Z]+$"},
+	},
+	"alnum": {
+		check: func(s string) bool {
+			return strings.IndexFunc(s, func(r rune) bool {
+				return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+			}) == -1
+		},
+		schema: Schema{Type: "string", Pattern: "^[a-zA-Z0-9]+$"},
+	},
+}
+
+// isUUID reports whether s is a canonical, hyphenated UUID.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parsePathParam parses the param in segment. ok is false if the segment is static.
+func parsePathParam(segment string) (p pathParam, ok bool, err error) {
+	start := strings.Index(segment, "{")
+	if start == -1 {
+		if strings.Contains(segment, "}") {
+			return p, false, fmt.Errorf("unexpected } in segment %s", segment)
+		}
+		return p, false, nil
+	}
+	// find the closing brace, allowing braces inside a regular expression constraint.
+	end, depth := -1, 0
+	for i := start; i < len(segment); i++ {
+		switch segment[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return p, false, fmt.Errorf("unclosed param in segment %s", segment)
+	}
+	p.Prefix = segment[:start]
+	p.Suffix = segment[end+1:]
+	if strings.ContainsAny(p.Suffix, "{}") {
+		return p, false, fmt.Errorf("only one param is allowed per segment, got %s", segment)
+	}
+
+	inner := segment[start+1 : end]
+	name, constraint, hasConstraint := strings.Cut(inner, ":")
+	if strings.HasSuffix(name, "...") && !hasConstraint {
+		p.CatchAll = true
+		name = strings.TrimSuffix(name, "...")
+		if p.Prefix != "" || p.Suffix != "" {
+			return p, false, fmt.Errorf("catch-all param %s must be the whole segment", name)
+		}
+	}
+	if name == "" {
+		return p, false, fmt.Errorf("param in segment %s has no name", segment)
+	}
+	p.Name = name
+	p.check = func(s string) bool { return s != "" }
+	p.schema = Schema{Type: "string"}
+
+	if hasConstraint {
+		if constraint == "" {
+			return p, false, fmt.Errorf("param %s has an empty constraint", name)
+		}
+		p.Constraint = constraint
+		if builtin, ok := builtinConstraints[constraint]; ok {
+			p.check = builtin.check
+			p.schema = builtin.schema
+		} else {
+			re, err := regexp.Compile("^(?:" + constraint + ")$")
+			if err != nil {
+				return p, false, fmt.Errorf("invalid constraint for param %s: %s", name, err.Error())
+			}
+			p.check = re.MatchString
+			p.schema = Schema{Type: "string", Pattern: constraint}
+		}
+	}
+	if p.CatchAll {
+		// a catch-all may capture nothing, e.g. "/static/" for "/static/{filepath...}".
+		p.check = func(string) bool { return true }
+	}
+	return p, true, nil
+}
+
+// parsePathParams returns every param declared in path, in order.
+func parsePathParams(path string) ([]pathParam, error) {
+	params := []pathParam{}
+	segments := splitPath(path)
+	for i, segment := range segments {
+		p, ok, err := parsePathParam(segment)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if p.CatchAll && i != len(segments)-1 {
+			return nil, fmt.Errorf("catch-all param %s must be the last segment of the path", p.Name)
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// buildPath substitutes values, in order, for the params declared in path. Values are
+// formatted with fmt.Sprint, checked against the param constraints and escaped.
+func buildPath(path string, values []any) (string, error) {
+	segments := splitPath(path)
+	index := 0
+	for i, segment := range segments {
+		p, ok, err := parsePathParam(segment)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			continue
+		}
+		if index >= len(values) {
+			return "", fmt.Errorf("missing value for param %s in %s", p.Name, path)
+		}
+		value := fmt.Sprint(values[index])
+		index++
+		if !p.check(value) {
+			if p.Constraint == "" {
+				return "", fmt.Errorf("value for param %s cannot be empty", p.Name)
+			}
+			return "", fmt.Errorf("value %s for param %s does not satisfy the constraint %s", value, p.Name, p.Constraint)
+		}
+		if p.CatchAll {
+			// a catch-all keeps its slashes; only the parts between them are escaped.
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+			continue
+		}
+		segments[i] = p.Prefix + url.PathEscape(value) + p.Suffix
+	}
+	if index != len(values) {
+		return "", fmt.Errorf("got %d values for %d params in %s", len(values), index, path)
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// openAPIPath converts a route path to an OpenAPI path template by dropping
+// constraints and catch-all markers, e.g. "/files/{path...}" to "/files/{path}".
+func openAPIPath(path string) string {
+	segments := splitPath(path)
+	for i, segment := range segments {
+		p, ok, err := parsePathParam(segment)
+		if err != nil || !ok {
+			continue
+		}
+		segments[i] = p.Prefix + "{" + p.Name + "}" + p.Suffix
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -20,6 +20,19 @@ type AppConfig struct {
 	TLSPrivateKeyFile string
 	// OpenAPI configuration. Gives users access to the OpenAPI spec generated. Can be manipulated by the user.
 	OpenAPI *OpenAPI
+	// TrailingSlash is the policy for paths that only differ from a route by a trailing slash.
+	TrailingSlash TrailingSlashPolicy
+	// CleanPath redirects requests with repeated slashes or . and .. elements to the clean path.
+	CleanPath bool
+	// CaseInsensitive matches the static segments of routes regardless of case.
+	CaseInsensitive bool
+	// Versioning enables API versioning. See Versioning.
+	Versioning *Versioning
+	// ErrorHandler sends the errors reported for requests. See PuffApp.ErrorHandler.
+	ErrorHandler ErrorHandler
+	// EnableFieldsCopy copies bound inputs to the Fields of routes. It races with
+	// concurrent requests. See PuffApp.EnableFieldsCopy.
+	EnableFieldsCopy bool
 }
 
 func App(c *AppConfig) *PuffApp {
@@ -36,6 +49,12 @@ func App(c *AppConfig) *PuffApp {
 		TLSPrivateKeyFile: c.TLSPrivateKeyFile,
 		RootRouter:        r,
 		OpenAPI:           c.OpenAPI,
+		TrailingSlash:     c.TrailingSlash,
+		CleanPath:         c.CleanPath,
+		CaseInsensitive:   c.CaseInsensitive,
+		Versioning:        c.Versioning,
+		ErrorHandler:      c.ErrorHandler,
+		EnableFieldsCopy:  c.EnableFieldsCopy,
 	}
 	a.RootRouter.puff = a
 	a.RootRouter.Responses = Responses{}
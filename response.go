@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"text/template"
 )
@@ -61,6 +62,12 @@ type Response interface {
 	WriteContent(*Context) error
 }
 
+// headerWriter is implemented by responses that need to set headers before the
+// status code is written.
+type headerWriter interface {
+	writeHeaders(*Context)
+}
+
 // JSONResponse represents a response with JSON content.
 type JSONResponse struct {
 	StatusCode int
@@ -87,6 +94,8 @@ func (j JSONResponse) WriteContent(c *Context) error {
 
 // HTMLResponse represents a response with HTML content.
 // It supports both file-based templates and inline string templates.
+// Templates can build the URL of a named route with the url function,
+// e.g. {{ url "pizza.get" .ID }}.
 type HTMLResponse struct {
 	StatusCode int
 	// Content to render if TemplateFile is not used.
@@ -114,13 +123,14 @@ func (h HTMLResponse) WriteContent(c *Context) error {
 	var tmpl *template.Template
 	var err error
 
+	funcs := template.FuncMap{"url": c.URLFor}
 	if h.TemplateFile != "" { // If TemplateFile is provided, use it.
-		tmpl, err = template.ParseFiles(h.TemplateFile)
+		tmpl, err = template.New(filepath.Base(h.TemplateFile)).Funcs(funcs).ParseFiles(h.TemplateFile)
 		if err != nil {
 			return fmt.Errorf("parsing template file failed: %s", err.Error())
 		}
 	} else if h.Template != "" { // If Template string is provided, use it.
-		tmpl, err = template.New("inlineTemplate").Parse(h.Template)
+		tmpl, err = template.New("inlineTemplate").Funcs(funcs).Parse(h.Template)
 		if err != nil {
 			return fmt.Errorf("parsing inline template failed: %s", err.Error())
 		}
@@ -269,9 +279,14 @@ func (r RedirectResponse) GetContentType() string {
 	return "text/html; charset=utf-8"
 }
 
-// WriteContent writes the header Location to redirect the client to.
-func (r RedirectResponse) WriteContent(c *Context) error {
+// writeHeaders writes the header Location to redirect the client to. It must be set
+// before the status code is written.
+func (r RedirectResponse) writeHeaders(c *Context) {
 	c.SetResponseHeader("Location", r.To)
+}
+
+// WriteContent writes a page that redirects clients that do not follow the Location header.
+func (r RedirectResponse) WriteContent(c *Context) error {
 	fmt.Fprintf(c.ResponseWriter, `<!DOCTYPE HTML>
     <html lang='en-US'>
     <head>
@@ -4,25 +4,79 @@ import (
 	"fmt"
 	"maps"
 	"reflect"
-	"regexp"
 	"strings"
 )
 
 type Route struct {
-	fullPath    string
-	regexp      *regexp.Regexp
-	params      []Parameter
+	fullPath string
+	params   []Parameter
+	// methods are the HTTP methods the route is served for. Set for routes registered
+	// with Any or Match; otherwise the route is served for Protocol only.
+	methods []string
+	// hidden routes are left out of the OpenAPI documentation.
+	hidden bool
+	// file and line are where the route was registered.
+	file string
+	line int
+	// Name identifies the route for URL generation. See WithName.
+	Name        string
 	Description string
 	WebSocket   bool
-	Protocol    string
-	Path        string
-	Handler     func(*Context)
-	Fields      any
+	// Protocol is the HTTP method of the route. Routes registered with Match list their
+	// methods separated by commas, and routes registered with Any use "ANY".
+	Protocol string
+	Path     string
+	Handler  func(*Context)
+	// Versions restricts the route to a range of API versions. If empty, the range of
+	// the closest router that sets one is used. Preferably set it using WithVersions.
+	Versions VersionRange
+	// Middlewares are applied to the route only, inside the middlewares of its routers.
+	// Preferably add middlewares using the Use method on Route.
+	Middlewares []*Middleware
+	// Fields is a pointer to the input struct of the route. Its fields describe the request
+	// input in the documentation, and a new struct of its type is bound for every request.
+	// The pointer is shared by every request, so the bound input is not written to it:
+	// read it with Input, or register routes with the generic Get, Post, Handle, etc.,
+	// which pass the bound input to the handler. See PuffApp.EnableFieldsCopy.
+	Fields any
 	// Router points to the router the route belongs to. Will always be the closest router in the tree.
 	Router *Router
 	// Responses are the schemas associated with a specific route. Have preference over parent router defined routes.
 	// Preferably set Responses using the WithResponse/WithResponses method on Route.
 	Responses Responses
+
+	// handler is Handler, along with the binding of its input, wrapped in the middlewares
+	// of the app, the routers and the route. It is built when the route is compiled.
+	handler HandlerFunc
+	// compiledFor are the routers above the route, innermost first, when it was
+	// compiled. If the route is moved to another router chain, a copy of it is compiled.
+	compiledFor []*Router
+	// source is the route this route is a copy of, if it was copied to be compiled again.
+	source *Route
+	// inputType is the type of the input struct bound for every request, or nil.
+	inputType reflect.Type
+	// typed routes pass the bound input to their handler and do not write it to Fields.
+	typed bool
+	// fromVersion and toVersion are the indices of the API versions the route serves.
+	fromVersion int
+	toVersion   int
+}
+
+// clone returns a copy of the route to compile again, leaving the route as it is for
+// the requests it may be serving.
+func (r *Route) clone() *Route {
+	route := *r
+	route.Responses = maps.Clone(r.Responses)
+	route.source = r.sourceOrSelf()
+	return &route
+}
+
+// sourceOrSelf returns the route the route is a copy of, or the route itself.
+func (r *Route) sourceOrSelf() *Route {
+	if r.source != nil {
+		return r.source
+	}
+	return r
 }
 
 func (r *Route) String() string {
@@ -33,7 +87,28 @@ func (r *Route) GetFullPath() string {
 	return r.fullPath
 }
 
+// registeredAt returns the file and line the route was registered at.
+func (r *Route) registeredAt() string {
+	if r.file == "" {
+		return "an unknown location"
+	}
+	return fmt.Sprintf("%s:%d", r.file, r.line)
+}
+
+// Methods returns the HTTP methods the route is served for.
+func (r *Route) Methods() []string {
+	if len(r.methods) == 0 {
+		return []string{r.Protocol}
+	}
+	return r.methods
+}
+
 func (route *Route) getCompletePath() {
+	route.fullPath = route.completePath()
+}
+
+// completePath returns the route path joined with the prefixes of its routers.
+func (route *Route) completePath() string {
 	var parts []string
 	currentRouter := route.Router
 	for currentRouter != nil {
@@ -42,18 +117,77 @@ func (route *Route) getCompletePath() {
 	}
 
 	parts = append(parts, route.Path)
-	route.fullPath = strings.Join(parts, "")
+	return strings.Join(parts, "")
+}
+
+// WithName names the route so its URL can be built with PuffApp.URLFor, and with the
+// url function in templates, instead of hardcoding its path.
+//
+// Example usage:
+//
+//	app.Get("/pizza/{id}", nil, handler).WithName("pizza.get")
+//	app.URLFor("pizza.get", 42) // "/pizza/42"
+func (r *Route) WithName(name string) *Route {
+	r.Name = name
+	return r
 }
 
-func (route *Route) createRegexMatch() {
-	escapedPath := strings.ReplaceAll(route.fullPath, "/", "\\/")
-	regexPattern := regexp.MustCompile(`\{[^}]+\}`).ReplaceAllString(escapedPath, "([^/]+)")
-	route.regexp = regexp.MustCompile("^" + regexPattern + "$")
+// Use adds a middleware to the route. Route middlewares run after the middlewares of
+// the route's routers, in the order they are added.
+//
+// Example usage:
+//
+//	app.Delete("/pizza/{id}", input, handler).Use(requireAdmin)
+func (r *Route) Use(m Middleware) *Route {
+	r.Middlewares = append(r.Middlewares, &m)
+	return r
+}
+
+// serve runs the handler of the route, wrapped in its middlewares, for the request on c.
+// params are the path param values captured while matching the request path.
+func (route *Route) serve(c *Context, params []string) {
+	c.params = params
+	if len(params) > 0 && strings.HasSuffix(route.fullPath, "...}") {
+		c.catchAll = params[len(params)-1]
+	}
+	if route.handler == nil {
+		// the route has not been compiled, so no middlewares apply yet.
+		route.handle(c)
+		return
+	}
+	route.handler(c)
+}
+
+// handle binds the request to the route's input schema and runs Handler. It runs inside
+// the middlewares of the route, so they see the errors of inputs that fail to bind.
+func (route *Route) handle(c *Context) {
+	if route.inputType != nil {
+		// every request gets its own input, so concurrent requests never share one.
+		in := reflect.New(route.inputType)
+		err := populateInputSchema(c, in.Interface(), route.params, c.params)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.input = in.Interface()
+		if !route.typed && c.puff != nil && c.puff.EnableFieldsCopy {
+			// handlers not yet migrated to Input read the input from Fields.
+			reflect.ValueOf(route.Fields).Elem().Set(in.Elem())
+		}
+	}
+	if route.WebSocket {
+		err := c.handleWebSocket()
+		if err != nil { // the message has already been passed on by the function; we may just return at this point
+			return
+		}
+	}
+	route.Handler(c)
 }
 
 func (route *Route) handleInputSchema() error { // should this return an error or should it panic?
 	if route.Fields == nil {
 		route.params = []Parameter{}
+		route.inputType = nil
 		return nil
 	}
 	sv := reflect.ValueOf(route.Fields) //
@@ -67,6 +201,12 @@ func (route *Route) handleInputSchema() error { // should this return an error o
 		return fmt.Errorf("fields must be pointer to STRUCT")
 	}
 
+	pathParams, err := parsePathParams(route.fullPath)
+	if err != nil {
+		return err
+	}
+	pathParamsIndex := 0
+
 	newParams := []Parameter{}
 	for i := range svet.NumField() {
 		newParam := Parameter{}
@@ -77,6 +217,16 @@ func (route *Route) handleInputSchema() error { // should this return an error o
 			name = svetf.Name
 		}
 
+		// param.rules
+		fieldRules, err := parseRules(svetf)
+		if err != nil {
+			return err
+		}
+		if err := checkRules(svetf.Type, map[reflect.Type]bool{}); err != nil {
+			return err
+		}
+		newParam.rules = fieldRules
+
 		// param.Schema
 		newParam.Schema = newDefinition(route, sve.Field(i).Interface())
 
@@ -86,7 +236,7 @@ func (route *Route) handleInputSchema() error { // should this return an error o
 			specified_kind = "body"
 		}
 		if !isValidKind(specified_kind) {
-			return fmt.Errorf("specified kind on field %s in struct tag must be header, path, query, cookie, body, or formdata", svetf.Name)
+			return fmt.Errorf("specified kind on field %s in struct tag must be header, path, query, cookie, body, form, file, or host", svetf.Name)
 		}
 
 		//param.Description
@@ -110,12 +260,45 @@ func (route *Route) handleInputSchema() error { // should this return an error o
 			return err
 		}
 
+		//param.Style and param.Explode
+		specified_style := svetf.Tag.Get("style")
+		specified_explode := svetf.Tag.Get("explode")
+		if svetf.Type.Kind() == reflect.Slice && specified_kind != "body" {
+			style, explode, err := resolveStyle(specified_kind, specified_style, specified_explode)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", svetf.Name, err)
+			}
+			newParam.Style = style
+			newParam.Explode = explode
+		} else if specified_style != "" || specified_explode != "" {
+			return fmt.Errorf("style and explode on field %s are only supported for slices", svetf.Name)
+		}
+
 		//param.Schema.format
 		format := svetf.Tag.Get("format")
-		if format != "" {
+		newParam.format = format
+		// the format of a type with a decoder is the layout it is decoded with, e.g. for time.Time.
+		if _, ok := decoderFor(svetf.Type); format != "" && !ok {
 			newParam.Schema.Format = format
 		}
 
+		// constraints declared in the path, e.g. {id:int}, describe the param better than the field type.
+		if specified_kind == "path" && pathParamsIndex < len(pathParams) {
+			pp := pathParams[pathParamsIndex]
+			if pp.Constraint != "" {
+				newParam.Schema.Type = pp.schema.Type
+				newParam.Schema.Pattern = pp.schema.Pattern
+				if pp.schema.Format != "" && format == "" {
+					newParam.Schema.Format = pp.schema.Format
+				}
+				if pp.schema.Minimum != "" {
+					newParam.Schema.Minimum = pp.schema.Minimum
+				}
+			}
+			pathParamsIndex++
+		}
+		fieldRules.document(&newParam.Schema)
+
 		newParam.Name = name
 		newParam.In = specified_kind
 		newParam.Description = description
@@ -125,28 +308,30 @@ func (route *Route) handleInputSchema() error { // should this return an error o
 		newParams = append(newParams, newParam)
 	}
 	route.params = newParams
+	route.inputType = svet
 	return nil
 }
 
 // GenerateResponses is responsible for generating the 'responses' attribute in the OpenAPI schema.
 // Since responses can be specified at multiple levels, responses at the route level will be given the most specificity.
 func (r *Route) GenerateResponses() {
-	if r.Router.puff.DocsURL == "" {
+	if r.Router.puff == nil || r.Router.puff.DocsURL == "" {
 		// if swagger documentation is off, we will not set responses
 		return
 	}
 
-	currentRouter := r.Router
-
-	for currentRouter != nil {
-		// avoid over-writing the original responses for the routers
-		clonedResponses := maps.Clone(currentRouter.Responses)
-		if clonedResponses == nil {
-			clonedResponses = make(Responses)
-		}
-		maps.Copy(clonedResponses, r.Responses)
-		currentRouter = currentRouter.parent
+	// responses of inner routers override those of outer routers, and the route's own
+	// responses override them all. The routers' responses are never modified.
+	var routers []*Router
+	for currentRouter := r.Router; currentRouter != nil; currentRouter = currentRouter.parent {
+		routers = append([]*Router{currentRouter}, routers...)
+	}
+	responses := make(Responses)
+	for _, router := range routers {
+		maps.Copy(responses, router.Responses)
 	}
+	maps.Copy(responses, r.Responses)
+	r.Responses = responses
 }
 
 // WithResponse registers a single response type for a specific HTTP status code
@@ -1,17 +1,28 @@
 package puff
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"path"
+	"reflect"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Router defines a group of routes that share the same prefix and middlewares.
 type Router struct {
-	Name        string
-	Prefix      string //(optional) prefix, all Routes underneath will have paths that start with the prefix automatically
+	Name   string
+	Prefix string //(optional) prefix, all Routes underneath will have paths that start with the prefix automatically
+	// Host (optional) restricts the routes underneath to requests for a host, e.g. "api.example.com".
+	// Labels in braces capture a value, e.g. "{tenant}.example.com", that can be bound with kind:"host".
+	// Sub-routers inherit the host unless they set their own.
+	Host        string
 	Routers     []*Router
 	Routes      []*Route
 	Middlewares []*Middleware
@@ -20,11 +31,49 @@ type Router struct {
 	// Responses is a map of status code to puff.Response. Possible Responses for routes can be set at the Router (root as well),
 	// and Route level, however responses directly set on the route will have the highest specificity.
 	Responses Responses
+	// NotFoundHandler handles requests under the router's prefix that match no route.
+	// If nil, the parent router's handler is used, down to a plain-text 404.
+	NotFoundHandler HandlerFunc
+	// MethodNotAllowedHandler handles requests whose path matches a route under the router
+	// but whose method does not. The Allow header is already set when it runs.
+	// If nil, the parent router's handler is used, down to a plain-text 405.
+	MethodNotAllowedHandler HandlerFunc
+	// Versions restricts the routes underneath to a range of API versions, unless they
+	// set their own. Sub-routers inherit the range unless they set their own.
+	Versions VersionRange
 
 	// parent maps to the router's immediate parent. Will be nil for RootRouter
 	parent *Router
 	// puff maps to the original PuffApp
 	puff *PuffApp
+	// mu serializes compiling and swapping the dispatch table of the router serving requests.
+	mu sync.Mutex
+	// dispatch is the compiled dispatch table. It is only built for the router serving requests.
+	dispatch atomic.Pointer[dispatchTable]
+}
+
+// dispatchTable is the compiled state a router serves requests from. It is replaced as
+// a whole when routes change, so requests in flight keep using the table they started with.
+type dispatchTable struct {
+	// tree is the routing tree for routes served on every host.
+	tree *node
+	// hosts are the routing trees for routes bound to a Host.
+	hosts []*hostTree
+	// routers mirrors the router tree, to find the router owning an unmatched path.
+	routers *routerEntry
+	// entries indexes routers by the router they describe.
+	entries map[*Router]*routerEntry
+}
+
+// routerEntry is a router in a dispatchTable as it was when the table was built: its
+// full prefix and its fallback handlers, already wrapped in its middlewares.
+type routerEntry struct {
+	router           *Router
+	prefix           string
+	children         []*routerEntry
+	notFound         HandlerFunc
+	methodNotAllowed HandlerFunc
+	options          HandlerFunc
 }
 
 // NewRouter creates a new router provided router name and path prefix.
@@ -36,14 +85,50 @@ func NewRouter(name string, prefix string) *Router {
 	}
 }
 
+// allMethods are the methods a route registered with Any is served for.
+var allMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// puffPackage is the import path of this package, used to skip its frames when looking
+// for the code that registered a route.
+var puffPackage = reflect.TypeFor[Router]().PkgPath()
+
+// registrationSite returns the file and line of the code that registered a route: the
+// first caller outside of this package.
+func registrationSite() (file string, line int, ok bool) {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, puffPackage+".") {
+			return frame.File, frame.Line, true
+		}
+		if !more {
+			return "", 0, false
+		}
+	}
+}
+
 func (r *Router) registerRoute(
 	method string,
 	path string,
 	handleFunc func(*Context),
 	fields any,
 ) *Route {
-	_, file, line, ok := runtime.Caller(2)
+	file, line, ok := registrationSite()
 	newRoute := Route{
+		file:        file,
+		line:        line,
 		Description: readDescription(file, line, ok),
 		Path:        path,
 		Handler:     handleFunc,
@@ -52,6 +137,12 @@ func (r *Router) registerRoute(
 		Router:      r,
 		Responses:   Responses{},
 	}
+	switch {
+	case method == "ANY":
+		newRoute.methods = allMethods
+	case strings.Contains(method, ","):
+		newRoute.methods = strings.Split(method, ",")
+	}
 
 	r.Routes = append(r.Routes, &newRoute)
 	return &newRoute
@@ -97,17 +188,79 @@ func (r *Router) Delete(
 	return r.registerRoute(http.MethodDelete, path, handleFunc, fields)
 }
 
+func (r *Router) Head(
+	path string,
+	fields any,
+	handleFunc func(*Context),
+) *Route {
+	return r.registerRoute(http.MethodHead, path, handleFunc, fields)
+}
+
+// Options registers an OPTIONS route. Without one, OPTIONS requests are answered
+// automatically with the Allow header for the path.
+func (r *Router) Options(
+	path string,
+	fields any,
+	handleFunc func(*Context),
+) *Route {
+	return r.registerRoute(http.MethodOptions, path, handleFunc, fields)
+}
+
+// Any registers a route served for every HTTP method.
+func (r *Router) Any(
+	path string,
+	fields any,
+	handleFunc func(*Context),
+) *Route {
+	return r.registerRoute("ANY", path, handleFunc, fields)
+}
+
+// Match registers a route served for each of the provided HTTP methods.
+func (r *Router) Match(
+	methods []string,
+	path string,
+	fields any,
+	handleFunc func(*Context),
+) *Route {
+	if len(methods) == 0 {
+		panic("Match requires at least one method for route " + path)
+	}
+	return r.registerRoute(strings.Join(methods, ","), path, handleFunc, fields)
+}
+
+// Mount serves h for every request under prefix, regardless of method. The prefix,
+// along with the router's own prefix, is stripped from the request path before h runs,
+// so an http.FileServer or a pprof mux can be mounted as is. The router's middlewares
+// are applied to h. Mounted handlers are not included in the OpenAPI documentation.
+//
+// Example usage:
+//
+//	router.Mount("/debug/pprof", http.DefaultServeMux)
+func (r *Router) Mount(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	handleFunc := mountHandler(h)
+	for _, path := range []string{prefix, prefix + "/{path...}"} {
+		route := r.registerRoute("ANY", path, handleFunc, nil)
+		route.hidden = true
+	}
+}
+
 func (r *Router) WebSocket(
 	path string,
 	fields any,
 	handleFunc func(*Context),
 ) *Route {
+	file, line, _ := registrationSite()
 	newRoute := Route{
 		WebSocket: true,
 		Protocol:  "GET",
+		file:      file,
+		line:      line,
 		Path:      path,
 		Handler:   handleFunc,
 		Fields:    fields,
+		Router:    r,
+		Responses: Responses{},
 	}
 	r.Routes = append(r.Routes, &newRoute)
 	return &newRoute
@@ -129,6 +282,102 @@ func (r *Router) IncludeRouter(rt *Router) {
 	r.Routers = append(r.Routers, rt)
 }
 
+// Group creates a sub-router under prefix and includes it in the router. fn configures
+// the group: its routes, middlewares, Tag, Description and Responses are set on g like
+// on any router, and the group is documented like any router. The group is named after
+// its prefix, or after the router for an empty prefix, unless fn sets g.Name.
+//
+// Example usage:
+//
+//	router.Group("/admin", func(g *puff.Router) {
+//		g.Tag = "Admin"
+//		g.Use(requireAdmin)
+//		g.Get("/stats", nil, statsHandler)
+//	})
+func (r *Router) Group(prefix string, fn func(g *Router)) *Router {
+	name := prefix
+	if name == "" {
+		name = r.Name
+	}
+	g := NewRouter(name, prefix)
+	r.IncludeRouter(g)
+	fn(g)
+	return g
+}
+
+// ExcludeRouter detaches rt from the router, so it can be included again elsewhere.
+// It reports whether rt was a sub-router of the router. On a running app, call it within
+// PuffApp.Update.
+func (r *Router) ExcludeRouter(rt *Router) bool {
+	i := slices.Index(r.Routers, rt)
+	if i == -1 {
+		return false
+	}
+	r.Routers = slices.Delete(slices.Clone(r.Routers), i, i+1)
+	rt.parent = nil
+	return true
+}
+
+// Unregister removes route from the router. It reports whether the route was registered
+// on the router. On a running app, call it within PuffApp.Update.
+func (r *Router) Unregister(route *Route) bool {
+	i := slices.IndexFunc(r.Routes, func(registered *Route) bool {
+		// a route moved with its router is registered as a copy of it.
+		return registered.sourceOrSelf() == route.sourceOrSelf()
+	})
+	if i == -1 {
+		return false
+	}
+	r.Routes = slices.Delete(slices.Clone(r.Routes), i, i+1)
+	return true
+}
+
+// routesSnapshot records the routes and sub-routers of every router in a tree, so
+// changes to the tree can be rolled back.
+type routesSnapshot []routerState
+
+type routerState struct {
+	router  *Router
+	routes  []*Route
+	routers []*Router
+}
+
+// snapshot records the routes and sub-routers of r and every router below it.
+func (r *Router) snapshot() routesSnapshot {
+	s := routesSnapshot{}
+	r.walk(func(router *Router) {
+		s = append(s, routerState{
+			router:  router,
+			routes:  slices.Clone(router.Routes),
+			routers: slices.Clone(router.Routers),
+		})
+	})
+	return s
+}
+
+// restore puts back the routes and sub-routers recorded by the snapshot. Routers included
+// since are detached, and routers excluded since are attached again.
+func (s routesSnapshot) restore() {
+	known := map[*Router]bool{}
+	for _, state := range s {
+		known[state.router] = true
+	}
+	for _, state := range s {
+		for _, sub := range state.router.Routers {
+			if !known[sub] {
+				sub.parent = nil
+			}
+		}
+	}
+	for _, state := range s {
+		state.router.Routes = state.routes
+		state.router.Routers = state.routers
+		for _, sub := range state.routers {
+			sub.parent = state.router
+		}
+	}
+}
+
 // Use adds a middleware to the router's list of middlewares. Middleware functions
 // can be used to intercept requests and responses, allowing for functionality such
 // as logging, authentication, and error handling to be applied to all routes managed
@@ -142,10 +391,10 @@ func (r *Router) IncludeRouter(rt *Router) {
 //
 // Parameters:
 // - m: A Middleware function that will be applied to all routes in this router.
-// TODO: dont know if below is actually accurate. cant think
-// Note: Middleware functions are executed in the order they are added. If multiple
-// middlewares are registered, they will be executed sequentially for each request
-// handled by the router.
+// Middlewares run from the outside in: those of the app first, then those of each
+// router down to the route's own router, and finally those added with Route.Use.
+// Within a router, middlewares run in the order they are added, so the first
+// middleware added is the outermost.
 func (r *Router) Use(m Middleware) {
 	r.Middlewares = append(r.Middlewares, &m)
 }
@@ -155,39 +404,366 @@ func (r *Router) String() string {
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	for _, router := range r.Routers {
-		if strings.HasPrefix(req.URL.Path, router.Prefix) {
-			router.ServeHTTP(w, req)
+	table := r.dispatch.Load()
+	if table == nil {
+		// the table is normally built by ListenAndServe; this covers routers served directly.
+		table = r.initDispatch()
+	}
+	c := NewContext(w, req)
+	c.puff = r.puff
+	defer c.handleError()
+	version, prefix, ok := r.resolveVersion(c)
+	if !ok {
+		return
+	}
+	req = c.Request
+	n, params, hostParams, redirect := r.resolve(table, req, version)
+	c.hostParams = hostParams
+	if redirect != "" {
+		redirect = prefix + redirect
+		u := *req.URL
+		u.Path = redirect
+		statusCode := http.StatusPermanentRedirect
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			statusCode = http.StatusMovedPermanently
+		}
+		c.SendResponse(RedirectResponse{StatusCode: statusCode, To: u.String()})
+		return
+	}
+	if n == nil {
+		table.notFound(c)
+		return
+	}
+	if route := n.route(req.Method, version); route != nil {
+		route.serve(c, params)
+		return
+	}
+	switch req.Method {
+	case http.MethodHead:
+		// HEAD is served by the GET route with the body discarded.
+		if route := n.route(http.MethodGet, version); route != nil {
+			c.ResponseWriter = &headResponseWriter{ResponseWriter: w}
+			route.serve(c, params)
 			return
 		}
+	case http.MethodOptions:
+		w.Header().Set("Allow", n.allowed())
+		table.entries[n.router()].options(c)
+		return
 	}
-	c := NewContext(w, req)
-	for _, route := range r.Routes {
-		if route.regexp == nil {
-			// TODO: need to fix this. this will be nil for the doc routes.
-			route.getCompletePath()
-			route.createRegexMatch()
-		}
-		isMatch := route.regexp.MatchString(req.URL.Path)
-		if isMatch && req.Method == route.Protocol {
-			matches := route.regexp.FindStringSubmatch(req.URL.Path)
-			err := populateInputSchema(c, route.Fields, route.params, matches)
-			if err != nil {
-				c.BadRequest(err.Error())
-				return
-			}
-			if route.WebSocket {
-				err := c.handleWebSocket()
-				if err != nil { // the message has already been passed on by the function; we may just return at this point
-					return
+	w.Header().Set("Allow", n.allowed())
+	table.entries[n.router()].methodNotAllowed(c)
+}
+
+// resolveVersion resolves the API version of the request when the app uses Versioning,
+// returning its index. If the version is in the path, the request on c is replaced by
+// one without it and the stripped prefix is returned. If the version is not supported,
+// a 400 is sent and ok is false.
+func (r *Router) resolveVersion(c *Context) (version int, prefix string, ok bool) {
+	if r.puff == nil || r.puff.Versioning == nil || len(r.puff.Versioning.Versions) == 0 {
+		return 0, "", true
+	}
+	v := r.puff.Versioning
+	version, prefix, err := v.resolve(c.Request)
+	if err != nil {
+		c.BadRequest(err.Error())
+		return 0, "", false
+	}
+	if prefix != "" {
+		req := c.Request.Clone(c.Request.Context())
+		req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, prefix), "/")
+		req.URL.RawPath = ""
+		c.Request = req
+	}
+	c.apiVersion = v.Versions[version].Name
+	for _, header := range v.vary() {
+		c.ResponseWriter.Header().Add("Vary", header)
+	}
+	v.Versions[version].writeHeaders(c.ResponseWriter.Header())
+	return version, prefix, true
+}
+
+// resolve finds the node serving req for the API version at index version. Routes bound to a matching Host are tried
+// before routes served on every host. If the client should be redirected instead,
+// the canonical path is returned.
+func (r *Router) resolve(table *dispatchTable, req *http.Request, version int) (n *node, params []string, hostParams map[string]string, redirect string) {
+	path := req.URL.Path
+	if r.puff != nil && r.puff.CleanPath {
+		if cleaned := cleanPath(path); cleaned != path {
+			return nil, nil, nil, cleaned
+		}
+	}
+	for _, ht := range table.hosts {
+		captures, ok := ht.pattern.match(req.Host)
+		if !ok {
+			continue
+		}
+		n, params, redirect = r.resolvePath(ht.tree, path, version)
+		if n != nil || redirect != "" {
+			return n, params, captures, redirect
+		}
+	}
+	n, params, redirect = r.resolvePath(table.tree, path, version)
+	return n, params, nil, redirect
+}
+
+// resolvePath looks up path in tree for the API version at index version, applying the
+// app's path policies. If the client should be redirected instead, the canonical path
+// is returned.
+func (r *Router) resolvePath(tree *node, path string, version int) (n *node, params []string, redirect string) {
+	app := r.puff
+	if app == nil {
+		n, params = tree.lookup(path, version, false)
+		return n, params, ""
+	}
+	n, params = tree.lookup(path, version, app.CaseInsensitive)
+	if n != nil || app.TrailingSlash == TrailingSlashStrict {
+		return n, params, ""
+	}
+
+	var alternate string
+	if strings.HasSuffix(path, "/") {
+		alternate = strings.TrimSuffix(path, "/")
+	} else {
+		alternate = path + "/"
+	}
+	if alternate == "" {
+		return nil, nil, ""
+	}
+	n, params = tree.lookup(alternate, version, app.CaseInsensitive)
+	if n != nil && app.TrailingSlash == TrailingSlashRedirect {
+		return nil, nil, alternate
+	}
+	return n, params, ""
+}
+
+// cleanPath returns the canonical form of path, removing repeated slashes and resolving
+// . and .. elements. A trailing slash is preserved.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// notFound responds to a request that matched no route. The router owning the path
+// handles it with its NotFoundHandler, through its middlewares.
+func (table *dispatchTable) notFound(c *Context) {
+	owner := table.routers.routerFor(c.Request.URL.Path)
+	slog.Debug(fmt.Sprintf("No route found for %s %s on router %s", c.Request.Method, c.Request.URL.Path, owner.router.Name))
+	owner.notFound(c)
+}
+
+// defaultNotFoundHandler is used when no router in the tree defines a NotFoundHandler.
+func defaultNotFoundHandler(c *Context) {
+	c.SendResponse(GenericResponse{
+		StatusCode: http.StatusNotFound,
+		Content:    "404 page not found",
+	})
+}
+
+// defaultMethodNotAllowedHandler is used when no router in the tree defines a MethodNotAllowedHandler.
+func defaultMethodNotAllowedHandler(c *Context) {
+	c.SendResponse(GenericResponse{
+		StatusCode: http.StatusMethodNotAllowed,
+		Content:    http.StatusText(http.StatusMethodNotAllowed),
+	})
+}
+
+// automaticOptions answers OPTIONS requests for paths without an OPTIONS route.
+// The Allow header is set before it runs.
+func automaticOptions(c *Context) {
+	c.SetStatusCode(http.StatusNoContent)
+}
+
+// resolveNotFoundHandler returns the closest NotFoundHandler, starting at the router itself.
+func (r *Router) resolveNotFoundHandler() HandlerFunc {
+	for current := r; current != nil; current = current.parent {
+		if current.NotFoundHandler != nil {
+			return current.NotFoundHandler
+		}
+	}
+	return defaultNotFoundHandler
+}
+
+// resolveMethodNotAllowedHandler returns the closest MethodNotAllowedHandler, starting at the router itself.
+func (r *Router) resolveMethodNotAllowedHandler() HandlerFunc {
+	for current := r; current != nil; current = current.parent {
+		if current.MethodNotAllowedHandler != nil {
+			return current.MethodNotAllowedHandler
+		}
+	}
+	return defaultMethodNotAllowedHandler
+}
+
+// wrap applies the middlewares of the router and its parents to handler, the same way
+// they are applied to the router's routes.
+func (r *Router) wrap(handler HandlerFunc) HandlerFunc {
+	var middlewares []*Middleware
+	for current := r; current != nil; current = current.parent {
+		middlewares = append(slices.Clone(current.Middlewares), middlewares...)
+	}
+	return chainMiddlewares(handler, middlewares)
+}
+
+// chainMiddlewares wraps handler in middlewares so that the first middleware is the
+// outermost, i.e. it runs first and sees the response last.
+func chainMiddlewares(handler HandlerFunc, middlewares []*Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = (*middlewares[i])(handler)
+	}
+	return handler
+}
+
+// fullPrefix returns the router's prefix joined with the prefixes of its parents.
+func (r *Router) fullPrefix() string {
+	prefix := ""
+	for current := r; current != nil; current = current.parent {
+		prefix = current.Prefix + prefix
+	}
+	return prefix
+}
+
+// routers returns the router and the routers above it, innermost first.
+func (r *Router) routers() []*Router {
+	routers := []*Router{}
+	for current := r; current != nil; current = current.parent {
+		routers = append(routers, current)
+	}
+	return routers
+}
+
+// ownsPath reports whether path falls under the router's prefix. Prefixes match on
+// whole segments, so a router with the prefix /user owns /user and /user/42 but
+// not /users/42.
+func (r *Router) ownsPath(path string) bool {
+	return prefixOwns(r.fullPrefix(), path)
+}
+
+// prefixOwns reports whether path falls under prefix, matching on whole segments.
+func prefixOwns(prefix string, path string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// routerFor returns the deepest router under e that owns path. When several sub-routers
+// own the path, the one with the longest prefix wins, falling back to e itself.
+func (e *routerEntry) routerFor(path string) *routerEntry {
+	var best *routerEntry
+	for _, sub := range e.children {
+		if !prefixOwns(sub.prefix, path) {
+			continue
+		}
+		if best == nil || len(sub.prefix) > len(best.prefix) {
+			best = sub
+		}
+	}
+	if best == nil {
+		return e
+	}
+	return best.routerFor(path)
+}
+
+// entry returns the router tree under r as it is now for a dispatchTable, adding every
+// router to entries.
+func (r *Router) entry(entries map[*Router]*routerEntry) *routerEntry {
+	e := &routerEntry{
+		router:           r,
+		prefix:           r.fullPrefix(),
+		notFound:         r.wrap(r.resolveNotFoundHandler()),
+		methodNotAllowed: r.wrap(r.resolveMethodNotAllowedHandler()),
+		options:          r.wrap(automaticOptions),
+	}
+	entries[r] = e
+	for _, sub := range r.Routers {
+		e.children = append(e.children, sub.entry(entries))
+	}
+	return e
+}
+
+// headResponseWriter discards the response body so GET handlers can answer HEAD requests.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *headResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for use with http.ResponseController.
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// initDispatch compiles the router and builds its dispatch table the first time the
+// router serves a request, if ListenAndServe has not done so.
+func (r *Router) initDispatch() *dispatchTable {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if table := r.dispatch.Load(); table != nil {
+		return table
+	}
+	if err := r.compile(nil); err != nil {
+		panic(err)
+	}
+	if err := r.buildTree(); err != nil {
+		panic(err)
+	}
+	return r.dispatch.Load()
+}
+
+// buildTree builds the dispatch table used by ServeHTTP from every compiled route under
+// the router and swaps it in. Routes bound to a Host get a tree per host pattern. Routes
+// that conflict with each other are reported together in the returned error, and the
+// current table is left unchanged.
+func (r *Router) buildTree() error {
+	tree := newNode()
+	hosts := []*hostTree{}
+	errs := []error{}
+	routes := r.AllRoutes()
+	for _, route := range routes {
+		target := tree
+		if host := route.resolveHost(); host != "" {
+			i := slices.IndexFunc(hosts, func(ht *hostTree) bool { return ht.pattern.Pattern == host })
+			if i == -1 {
+				pattern, err := parseHostPattern(host)
+				if err != nil {
+					return err
 				}
+				hosts = append(hosts, &hostTree{pattern: pattern, tree: newNode()})
+				i = len(hosts) - 1
 			}
-			handler := route.Handler
-			handler(c)
-			return
+			target = hosts[i].tree
 		}
+		err := target.insert(route)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, checkRouteNames(routes)...)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
-	http.NotFound(w, req)
+	// static hosts are more specific than hosts with params and are tried first.
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return hosts[i].pattern.params() < hosts[j].pattern.params()
+	})
+	entries := map[*Router]*routerEntry{}
+	routers := r.entry(entries)
+	r.dispatch.Store(&dispatchTable{tree: tree, hosts: hosts, routers: routers, entries: entries})
+	return nil
 }
 
 func Unprocessable(w http.ResponseWriter, r *http.Request) {
@@ -207,17 +783,56 @@ func (r *Router) AllRoutes() []*Route {
 	return routes
 }
 
-func (r *Router) patchRoutes() {
-	for _, route := range r.Routes {
+// compile prepares the routes of the router, and of every router below it at any depth,
+// for serving. It links each router to its parent and app, computes the full path of
+// each route, validates its input schema, generates its responses and wraps its handler
+// in the middlewares of its routers and its own. middlewares are the middlewares inherited
+// from the routers above r. Every route that fails to compile is reported in the returned error.
+//
+// Routes are compiled once: compiling again, e.g. after routes are added to a running
+// app, only compiles the new routes and never touches routes that may be serving requests.
+// Routes whose routers were moved are compiled as copies, which replace them in r.Routes.
+func (r *Router) compile(middlewares []*Middleware) error {
+	chain := append(slices.Clone(middlewares), r.Middlewares...)
+	routers := r.routers()
+	errs := []error{}
+	copied := false
+	for i, route := range r.Routes {
+		if route.handler != nil {
+			if slices.Equal(route.compiledFor, routers) {
+				continue
+			}
+			// the route may still be serving requests under its previous routers.
+			if !copied {
+				r.Routes = slices.Clone(r.Routes)
+				copied = true
+			}
+			route = route.clone()
+			r.Routes[i] = route
+		}
 		route.Router = r
 		route.getCompletePath()
-		route.createRegexMatch()
 		err := route.handleInputSchema()
 		if err != nil {
-			panic("error with Input Schema for route " + route.Path + " on router " + r.Name + ". Error: " + err.Error())
+			errs = append(errs, fmt.Errorf("error with Input Schema for route %s on router %s registered at %s: %s", route.Path, r.Name, route.registeredAt(), err.Error()))
+			continue
+		}
+		err = route.resolveVersions()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error with versions of route %s on router %s registered at %s: %s", route.Path, r.Name, route.registeredAt(), err.Error()))
+			continue
 		}
-		slog.Debug(fmt.Sprintf("Serving route: %s", route.fullPath))
 		// populate route with their respective responses
 		route.GenerateResponses()
+		route.handler = chainMiddlewares(route.handle, append(slices.Clone(chain), route.Middlewares...))
+		route.compiledFor = routers
+	}
+	for _, sub := range r.Routers {
+		if sub.parent != r || sub.puff != r.puff {
+			sub.parent = r
+			sub.puff = r.puff
+		}
+		errs = append(errs, sub.compile(chain))
 	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,1204 @@
+package puff_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ThePuffProject/puff"
+	"github.com/ThePuffProject/puff/middleware"
+)
+
+// testrouterapp builds an app with a handful of routes that respond with the
+// name of the route that handled the request.
+func testrouterapp() *puff.PuffApp {
+	app := puff.App(&puff.AppConfig{Name: "router test"})
+	app.Get("/", nil, respond("root"))
+	app.Get("/pizza", nil, respond("pizza"))
+	app.Get("/pizza/{id}", nil, respond("pizza id"))
+	app.Get("/pizza/special", nil, respond("pizza special"))
+	app.Post("/pizza/{id}", nil, respond("pizza id post"))
+	app.Get("/files/{name}.json", nil, respond("files json"))
+	app.Get("/files/{name}", nil, respond("files"))
+
+	users := puff.NewRouter("Users", "/users")
+	users.Get("/{id}", nil, respond("user"))
+	users.Get("/{id}/orders/{order}", nil, respond("user order"))
+	users.Get("/me/orders/latest", nil, respond("my latest order"))
+	app.IncludeRouter(users)
+	return app
+}
+
+// testlisten builds app and serves it on a test server, returning its base URL.
+func testlisten(t *testing.T, app *puff.PuffApp) string {
+	if err := app.Build(); err != nil {
+		t.Fatalf("unexpected error building the app: %s", err.Error())
+	}
+	server := httptest.NewServer(app.RootRouter)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// respond returns a handler that responds with name.
+func respond(name string) func(*puff.Context) {
+	return func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: name})
+	}
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRouterMatch(t *testing.T) {
+	app := testrouterapp()
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodGet, "/", "root"},
+		{http.MethodGet, "/pizza", "pizza"},
+		{http.MethodGet, "/pizza/12", "pizza id"},
+		{http.MethodPost, "/pizza/12", "pizza id post"},
+		{http.MethodGet, "/pizza/special", "pizza special"},
+		{http.MethodGet, "/files/menu.json", "files json"},
+		{http.MethodGet, "/files/menu.txt", "files"},
+		{http.MethodGet, "/users/42", "user"},
+		{http.MethodGet, "/users/42/orders/7", "user order"},
+		{http.MethodGet, "/users/me/orders/latest", "my latest order"},
+		// static segments are preferred, but matching falls back to params.
+		{http.MethodGet, "/users/me/orders/7", "user order"},
+	}
+	for _, test := range tests {
+		w := serve(app.RootRouter, test.method, test.path)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: expected status 200, got %d", test.method, test.path, w.Code)
+			continue
+		}
+		if w.Body.String() != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.method, test.path, test.expected, w.Body.String())
+		}
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	app := testrouterapp()
+	for _, path := range []string{"/pizza/", "/pizza/12/toppings", "/users", "/unknown", "/files/"} {
+		w := serve(app.RootRouter, http.MethodGet, path)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected status 404, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestRouterConstraints(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "constraints"})
+	app.Get("/pizza/{id:int}", nil, respond("pizza id"))
+	app.Get("/pizza/{slug:[a-z-]+}", nil, respond("pizza slug"))
+	app.Get("/orders/{id:uuid}", nil, respond("order"))
+	app.Get("/static/{filepath...}", nil, respond("static"))
+	app.Get("/static/robots.txt", nil, respond("robots"))
+
+	tests := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/pizza/12", http.StatusOK, "pizza id"},
+		{"/pizza/margherita-special", http.StatusOK, "pizza slug"},
+		{"/pizza/Margherita", http.StatusNotFound, ""},
+		{"/orders/4f1d2c3b-8a9e-4b7c-9d6e-1a2b3c4d5e6f", http.StatusOK, "order"},
+		{"/orders/12", http.StatusNotFound, ""},
+		{"/static/css/site/main.css", http.StatusOK, "static"},
+		{"/static/", http.StatusOK, "static"},
+		{"/static/robots.txt", http.StatusOK, "robots"},
+		{"/static", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := serve(app.RootRouter, http.MethodGet, test.path)
+		if w.Code != test.status {
+			t.Errorf("GET %s: expected status %d, got %d", test.path, test.status, w.Code)
+			continue
+		}
+		if test.status == http.StatusOK && w.Body.String() != test.expected {
+			t.Errorf("GET %s: expected %q, got %q", test.path, test.expected, w.Body.String())
+		}
+	}
+}
+
+func TestRouterMethods(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "methods"})
+	app.Get("/pizza", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: "pizza"})
+	})
+	app.Post("/pizza", nil, func(c *puff.Context) {})
+	app.Any("/anything", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: c.Request.Method})
+	})
+	app.Match([]string{http.MethodPut, http.MethodPatch}, "/toppings", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: c.Request.Method})
+	})
+	app.Options("/custom", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{StatusCode: http.StatusOK, Content: "custom options"})
+	})
+
+	w := serve(app.RootRouter, http.MethodDelete, "/pizza")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("expected Allow header %q, got %q", "GET, HEAD, OPTIONS, POST", allow)
+	}
+
+	w = serve(app.RootRouter, http.MethodHead, "/pizza")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("expected HEAD to return 200 without a body, got %d with %q", w.Code, w.Body.String())
+	}
+
+	w = serve(app.RootRouter, http.MethodOptions, "/pizza")
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("expected automatic OPTIONS response, got %d with Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = serve(app.RootRouter, http.MethodOptions, "/custom")
+	if w.Body.String() != "custom options" {
+		t.Errorf("expected OPTIONS route to override automatic response, got %q", w.Body.String())
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete, http.MethodTrace} {
+		w = serve(app.RootRouter, method, "/anything")
+		if w.Body.String() != method {
+			t.Errorf("expected Any route to serve %s, got %q", method, w.Body.String())
+		}
+	}
+
+	w = serve(app.RootRouter, http.MethodPatch, "/toppings")
+	if w.Body.String() != http.MethodPatch {
+		t.Errorf("expected Match route to serve PATCH, got %q", w.Body.String())
+	}
+	w = serve(app.RootRouter, http.MethodGet, "/toppings")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "OPTIONS, PATCH, PUT" {
+		t.Errorf("expected 405 with Allow %q, got %d with %q", "OPTIONS, PATCH, PUT", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestRouterPrefixes(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "prefixes"})
+	user := puff.NewRouter("User", "/user")
+	user.Get("/{id}", nil, respond("user"))
+	app.IncludeRouter(user)
+	users := puff.NewRouter("Users", "/users")
+	users.Get("/{id}", nil, respond("users"))
+	app.IncludeRouter(users)
+
+	api := puff.NewRouter("API", "/api")
+	api.Get("/status", nil, respond("api status"))
+	app.IncludeRouter(api)
+	apiDocs := puff.NewRouter("API Docs", "/api-docs")
+	apiDocs.Get("", nil, respond("api docs"))
+	app.IncludeRouter(apiDocs)
+
+	// a sub-router without a prefix must not swallow routes registered after it.
+	misc := puff.NewRouter("Misc", "")
+	misc.Get("/health", nil, respond("health"))
+	app.IncludeRouter(misc)
+
+	// routes on a parent are reached when the sub-router has no matching route.
+	app.Get("/api/{rest...}", nil, respond("api fallback"))
+	app.Get("/version", nil, respond("version"))
+
+	tests := map[string]string{
+		"/user/42":      "user",
+		"/users/42":     "users",
+		"/api/status":   "api status",
+		"/api-docs":     "api docs",
+		"/health":       "health",
+		"/version":      "version",
+		"/api/v2/thing": "api fallback",
+	}
+	for path, expected := range tests {
+		w := serve(app.RootRouter, http.MethodGet, path)
+		if w.Body.String() != expected {
+			t.Errorf("GET %s: expected %q, got %d %q", path, expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRouterFallbackHandlers(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "fallbacks"})
+	app.Use(func(next puff.HandlerFunc) puff.HandlerFunc {
+		return func(c *puff.Context) {
+			c.SetResponseHeader("X-Middleware", "root")
+			next(c)
+		}
+	})
+	app.Get("/pizza", nil, func(c *puff.Context) {})
+
+	api := puff.NewRouter("API", "/api")
+	api.NotFoundHandler = func(c *puff.Context) {
+		c.NotFound("no route for %s", c.Request.URL.Path)
+	}
+	api.MethodNotAllowedHandler = func(c *puff.Context) {
+		c.SendResponse(puff.JSONResponse{
+			StatusCode: http.StatusMethodNotAllowed,
+			Content:    map[string]string{"allow": c.GetResponseHeader("Allow")},
+		})
+	}
+	api.Get("/status", nil, func(c *puff.Context) {})
+	v1 := puff.NewRouter("V1", "/v1")
+	v1.Get("/users", nil, func(c *puff.Context) {})
+	api.IncludeRouter(v1)
+	app.IncludeRouter(api)
+
+	// the root router uses the default handlers.
+	w := serve(app.RootRouter, http.MethodGet, "/unknown")
+	if w.Code != http.StatusNotFound || w.Header().Get("X-Middleware") != "root" {
+		t.Errorf("expected 404 through the root middleware, got %d with header %q", w.Code, w.Header().Get("X-Middleware"))
+	}
+	w = serve(app.RootRouter, http.MethodPost, "/pizza")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("X-Middleware") != "root" {
+		t.Errorf("expected 405 through the root middleware, got %d with header %q", w.Code, w.Header().Get("X-Middleware"))
+	}
+
+	// sub-routers inherit the handlers of their parents.
+	for _, path := range []string{"/api/unknown", "/api/v1/unknown"} {
+		w = serve(app.RootRouter, http.MethodGet, path)
+		expected := fmt.Sprintf(`{"error":"no route for %s"}`, path)
+		if w.Code != http.StatusNotFound || strings.TrimSpace(w.Body.String()) != expected {
+			t.Errorf("GET %s: expected JSON 404 %s, got %d %s", path, expected, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Middleware") != "root" {
+			t.Errorf("GET %s: expected NotFoundHandler to run through middlewares", path)
+		}
+	}
+	w = serve(app.RootRouter, http.MethodDelete, "/api/v1/users")
+	if w.Code != http.StatusMethodNotAllowed || strings.TrimSpace(w.Body.String()) != `{"allow":"GET, HEAD, OPTIONS"}` {
+		t.Errorf("expected JSON 405 listing allowed methods, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRouterPathPolicies(t *testing.T) {
+	newapp := func(config puff.AppConfig) *puff.PuffApp {
+		app := puff.App(&config)
+		app.Get("/pizza", nil, func(c *puff.Context) {
+			c.SendResponse(puff.GenericResponse{Content: "pizza"})
+		})
+		app.Get("/toppings/", nil, func(c *puff.Context) {
+			c.SendResponse(puff.GenericResponse{Content: "toppings"})
+		})
+		app.Post("/orders", nil, func(c *puff.Context) {})
+		app.Get("/users/{name}", nil, func(c *puff.Context) {
+			c.SendResponse(puff.GenericResponse{Content: "user " + c.Request.URL.Path})
+		})
+		return app
+	}
+
+	strict := newapp(puff.AppConfig{})
+	for _, path := range []string{"/pizza/", "/toppings", "//pizza", "/PIZZA"} {
+		if w := serve(strict.RootRouter, http.MethodGet, path); w.Code != http.StatusNotFound {
+			t.Errorf("strict GET %s: expected 404, got %d", path, w.Code)
+		}
+	}
+
+	redirect := newapp(puff.AppConfig{TrailingSlash: puff.TrailingSlashRedirect, CleanPath: true})
+	tests := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/pizza/", http.StatusMovedPermanently, "/pizza"},
+		{http.MethodGet, "/toppings?extra=cheese", http.StatusMovedPermanently, "/toppings/?extra=cheese"},
+		{http.MethodPost, "/orders/", http.StatusPermanentRedirect, "/orders"},
+		{http.MethodGet, "//pizza", http.StatusMovedPermanently, "/pizza"},
+		{http.MethodGet, "/a/../pizza", http.StatusMovedPermanently, "/pizza"},
+	}
+	for _, test := range tests {
+		w := serve(redirect.RootRouter, test.method, test.path)
+		if w.Code != test.status || w.Header().Get("Location") != test.location {
+			t.Errorf("%s %s: expected %d to %q, got %d to %q", test.method, test.path, test.status, test.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	match := newapp(puff.AppConfig{TrailingSlash: puff.TrailingSlashMatch, CaseInsensitive: true})
+	for path, expected := range map[string]string{
+		"/pizza/":      "pizza",
+		"/Pizza":       "pizza",
+		"/toppings":    "toppings",
+		"/USERS/Alice": "user /USERS/Alice",
+	} {
+		w := serve(match.RootRouter, http.MethodGet, path)
+		if w.Code != http.StatusOK || w.Body.String() != expected {
+			t.Errorf("match GET %s: expected %q, got %d %q", path, expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRouterHosts(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "hosts"})
+	app.Get("/status", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: "default"})
+	})
+
+	api := puff.NewRouter("API", "")
+	api.Host = "api.example.com"
+	api.Get("/status", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: "api"})
+	})
+	app.IncludeRouter(api)
+
+	tenants := puff.NewRouter("Tenants", "")
+	tenants.Host = "{tenant}.example.com"
+	tenants.Get("/status", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: "tenant " + c.GetHostParam("tenant")})
+	})
+	app.IncludeRouter(tenants)
+
+	tests := map[string]string{
+		"api.example.com":      "api",
+		"API.example.com:8080": "api",
+		"acme.example.com":     "tenant acme",
+		"example.com":          "default",
+		"a.b.example.com":      "default",
+	}
+	for host, expected := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		app.RootRouter.ServeHTTP(w, req)
+		if w.Body.String() != expected {
+			t.Errorf("GET %s/status: expected %q, got %q", host, expected, w.Body.String())
+		}
+	}
+
+	servers := app.GenerateServers()
+	if len(servers) != 3 || servers[1].URL != "http://api.example.com" || servers[2].URL != "http://{tenant}.example.com" {
+		t.Errorf("expected a server per host pattern, got %+v", servers)
+	}
+	if _, ok := servers[2].Variables["tenant"]; !ok {
+		t.Errorf("expected the tenant server variable to be defined")
+	}
+}
+
+func TestRouterMount(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "mount"})
+	legacy := http.NewServeMux()
+	legacy.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		fmt.Fprintf(w, "legacy %s %s", r.Method, r.URL.Path)
+	})
+	api := puff.NewRouter("API", "/api")
+	// net/http middleware adapted to puff.
+	api.Use(puff.FromHTTPMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Legacy-Middleware", "yes")
+			next.ServeHTTP(w, r)
+		})
+	}))
+	api.Mount("/legacy", legacy)
+	app.IncludeRouter(api)
+
+	base := testlisten(t, app)
+	for path, expected := range map[string]string{
+		"/api/legacy":               "legacy POST /",
+		"/api/legacy/users/42":      "legacy POST /users/42",
+		"/api/legacy/users/42/edit": "legacy POST /users/42/edit",
+	} {
+		res, err := http.Post(base+path, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusTeapot || string(body) != expected {
+			t.Errorf("POST %s: expected 418 %q, got %d %q", path, expected, res.StatusCode, string(body))
+		}
+		if res.Header.Get("X-Legacy-Middleware") != "yes" {
+			t.Errorf("POST %s: expected adapted net/http middleware to run", path)
+		}
+	}
+
+	// the mount strips what its prefix matched, not the prefix as registered.
+	folded := puff.App(&puff.AppConfig{Name: "mount", CaseInsensitive: true})
+	folded.Mount("/api/legacy", legacy)
+	escaped := puff.NewRouter("Escaped", "/escaped")
+	escaped.Mount("/raw", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.EscapedPath())
+	}))
+	folded.IncludeRouter(escaped)
+	w := serve(folded.RootRouter, http.MethodGet, "/API/Legacy/Users/42")
+	if w.Body.String() != "legacy GET /Users/42" {
+		t.Errorf("expected the prefix to be stripped case-insensitively, got %q", w.Body.String())
+	}
+	if w := serve(folded.RootRouter, http.MethodGet, "/escaped/raw/menu%2Fpizza/1"); w.Body.String() != "/menu%2Fpizza/1" {
+		t.Errorf("expected the escaped path to be kept, got %q", w.Body.String())
+	}
+
+	// puff middleware adapted to net/http.
+	tracing := puff.ToHTTPMiddleware(func(next puff.HandlerFunc) puff.HandlerFunc {
+		return func(c *puff.Context) {
+			c.SetResponseHeader("X-Puff-Middleware", "yes")
+			next(c)
+		}
+	})
+	w = serve(tracing(legacy), http.MethodGet, "/anything")
+	if w.Header().Get("X-Puff-Middleware") != "yes" || w.Body.String() != "legacy GET /anything" {
+		t.Errorf("expected puff middleware to wrap the net/http handler, got %q", w.Body.String())
+	}
+
+	// errors reported by an adapted puff middleware are sent.
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("burnt crust") })
+	w = serve(puff.ToHTTPMiddleware(middleware.Panic())(panicking), http.MethodGet, "/anything")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Errorf("expected the recovered panic to be sent as an error, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "urls"})
+	menu := puff.NewRouter("Menu", "/menu")
+	menu.Get("/pizza/{id:int}", nil, func(c *puff.Context) {}).WithName("pizza.get")
+	menu.Get("/pizza/{name}/toppings", nil, func(c *puff.Context) {}).WithName("pizza.toppings")
+	menu.Get("/files/{path...}", nil, func(c *puff.Context) {}).WithName("files")
+	menu.Get("/page", nil, func(c *puff.Context) {
+		c.SendResponse(puff.HTMLResponse{Template: `<a href="{{ url "pizza.get" .ID }}">pizza</a>`, Data: map[string]int{"ID": 7}})
+	})
+	app.IncludeRouter(menu)
+
+	tests := []struct {
+		name     string
+		params   []any
+		expected string
+	}{
+		{"pizza.get", []any{42}, "/menu/pizza/42"},
+		{"pizza.toppings", []any{"four cheese/extra"}, "/menu/pizza/four%20cheese%2Fextra/toppings"},
+		{"files", []any{"css/site main.css"}, "/menu/files/css/site%20main.css"},
+	}
+	for _, test := range tests {
+		url, err := app.URLFor(test.name, test.params...)
+		if err != nil {
+			t.Errorf("URLFor %s: unexpected error: %s", test.name, err.Error())
+			continue
+		}
+		if url != test.expected {
+			t.Errorf("URLFor %s: expected %q, got %q", test.name, test.expected, url)
+		}
+	}
+
+	// prefixes are resolved when the URL is built, not when the route is registered.
+	menu.Prefix = "/v2/menu"
+	if url, _ := app.URLFor("pizza.get", 1); url != "/v2/menu/pizza/1" {
+		t.Errorf("expected URLFor to follow prefix changes, got %q", url)
+	}
+
+	for _, params := range [][]any{{"margherita"}, {}, {1, 2}} {
+		if _, err := app.URLFor("pizza.get", params...); err == nil {
+			t.Errorf("URLFor pizza.get with %v: expected an error", params)
+		}
+	}
+	if _, err := app.URLFor("unknown"); err == nil {
+		t.Errorf("expected an error for an unknown route name")
+	}
+
+	w := serve(app.RootRouter, http.MethodGet, "/v2/menu/page")
+	if w.Body.String() != `<a href="/v2/menu/pizza/7">pizza</a>` {
+		t.Errorf("expected the url template function to build the link, got %q", w.Body.String())
+	}
+}
+
+// benchmarkroutes registers n routes with a mix of static and param segments
+// and returns the paths to request.
+func benchmarkroutes(n int, register func(path string)) []string {
+	var paths []string
+	for i := range n {
+		switch i % 3 {
+		case 0:
+			register(fmt.Sprintf("/resource%d", i))
+			paths = append(paths, fmt.Sprintf("/resource%d", i))
+		case 1:
+			register(fmt.Sprintf("/resource%d/{id}", i))
+			paths = append(paths, fmt.Sprintf("/resource%d/42", i))
+		case 2:
+			register(fmt.Sprintf("/resource%d/{id}/items/{item}", i))
+			paths = append(paths, fmt.Sprintf("/resource%d/42/items/7", i))
+		}
+	}
+	return paths
+}
+
+func BenchmarkRouterTree(b *testing.B) {
+	app := puff.App(&puff.AppConfig{Name: "bench"})
+	paths := benchmarkroutes(300, func(path string) {
+		app.Get(path, nil, func(c *puff.Context) {})
+	})
+	w := httptest.NewRecorder()
+	requests := make([]*http.Request, len(paths))
+	for i, path := range paths {
+		requests[i] = httptest.NewRequest(http.MethodGet, path, nil)
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		app.RootRouter.ServeHTTP(w, requests[i%len(requests)])
+	}
+}
+
+// BenchmarkRouterRegex measures the linear regex scan Router.ServeHTTP used
+// before the routing tree, for comparison with BenchmarkRouterTree.
+func BenchmarkRouterRegex(b *testing.B) {
+	type regexroute struct {
+		re      *regexp.Regexp
+		handler func(*puff.Context)
+	}
+	var routes []regexroute
+	paramRe := regexp.MustCompile(`\{[^}]+\}`)
+	paths := benchmarkroutes(300, func(path string) {
+		escaped := strings.ReplaceAll(path, "/", "\\/")
+		routes = append(routes, regexroute{
+			re:      regexp.MustCompile("^" + paramRe.ReplaceAllString(escaped, "([^/]+)") + "$"),
+			handler: func(c *puff.Context) {},
+		})
+	})
+	w := httptest.NewRecorder()
+	requests := make([]*http.Request, len(paths))
+	for i, path := range paths {
+		requests[i] = httptest.NewRequest(http.MethodGet, path, nil)
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		req := requests[i%len(requests)]
+		c := puff.NewContext(w, req)
+		for _, route := range routes {
+			if route.re.MatchString(req.URL.Path) {
+				route.re.FindStringSubmatch(req.URL.Path)
+				route.handler(c)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkRouterServeMux(b *testing.B) {
+	mux := http.NewServeMux()
+	paths := benchmarkroutes(300, func(path string) {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {})
+	})
+	w := httptest.NewRecorder()
+	requests := make([]*http.Request, len(paths))
+	for i, path := range paths {
+		requests[i] = httptest.NewRequest(http.MethodGet, path, nil)
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		mux.ServeHTTP(w, requests[i%len(requests)])
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(app *puff.PuffApp)
+		expected []string
+	}{
+		{
+			name: "duplicate",
+			register: func(app *puff.PuffApp) {
+				app.Get("/pizza", nil, func(c *puff.Context) {})
+				app.Get("/pizza", nil, func(c *puff.Context) {})
+			},
+			expected: []string{"duplicate route GET /pizza"},
+		},
+		{
+			name: "param names",
+			register: func(app *puff.PuffApp) {
+				app.Get("/pizza/{id}", nil, func(c *puff.Context) {})
+				app.Get("/pizza/{name}", nil, func(c *puff.Context) {})
+			},
+			expected: []string{"ambiguous routes GET /pizza/{id}", "GET /pizza/{name}"},
+		},
+		{
+			name: "sub-router",
+			register: func(app *puff.PuffApp) {
+				app.Any("/menu/pizza", nil, func(c *puff.Context) {})
+				menu := puff.NewRouter("Menu", "/menu")
+				menu.Post("/pizza", nil, func(c *puff.Context) {})
+				app.IncludeRouter(menu)
+			},
+			expected: []string{"duplicate route POST /menu/pizza"},
+		},
+		{
+			name: "names",
+			register: func(app *puff.PuffApp) {
+				app.Get("/pizza", nil, func(c *puff.Context) {}).WithName("pizza")
+				app.Get("/pasta", nil, func(c *puff.Context) {}).WithName("pizza")
+			},
+			expected: []string{"duplicate route name pizza"},
+		},
+	}
+	for _, test := range tests {
+		app := puff.App(&puff.AppConfig{Name: "conflicts"})
+		test.register(app)
+		err := app.Build()
+		if err == nil {
+			t.Errorf("%s: expected an error", test.name)
+			continue
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("%s: expected the error to contain %q, got %q", test.name, expected, err.Error())
+			}
+		}
+		// both registration sites are reported.
+		if sites := regexp.MustCompile(`router_test\.go:\d+`).FindAllString(err.Error(), -1); len(sites) != 2 {
+			t.Errorf("%s: expected both registration sites in the error, got %q", test.name, err.Error())
+		}
+	}
+
+	// the same path with different methods, params with different constraints and static
+	// segments next to params are not conflicts.
+	app := puff.App(&puff.AppConfig{Name: "no conflicts"})
+	app.Get("/pizza/{id}", nil, func(c *puff.Context) {})
+	app.Post("/pizza/{id}", nil, func(c *puff.Context) {})
+	app.Get("/pizza/{id:int}", nil, func(c *puff.Context) {})
+	app.Get("/pizza/special", nil, func(c *puff.Context) {})
+	if err := app.Build(); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+}
+
+func TestRouterStatic(t *testing.T) {
+	assets := fstest.MapFS{
+		"index.html":         {Data: []byte("home")},
+		"css/site.css":       {Data: []byte("body {}")},
+		"docs/index.html":    {Data: []byte("docs")},
+		"images/logo.svg":    {Data: []byte("<svg></svg>")},
+		"images/icon 1.png":  {Data: []byte("png")},
+		"downloads/menu.txt": {Data: []byte("margherita, marinara")},
+	}
+	app := puff.App(&puff.AppConfig{Name: "static"})
+	app.Static("/assets", assets, puff.StaticConfig{Browse: true})
+	app.Static("/app", assets, puff.StaticConfig{SPA: true})
+
+	tests := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/assets/css/site.css", http.StatusOK, "body {}"},
+		{"/assets", http.StatusMovedPermanently, ""},
+		{"/assets/", http.StatusOK, "home"},
+		{"/assets/docs/", http.StatusOK, "docs"},
+		{"/assets/missing.css", http.StatusNotFound, "404 page not found"},
+		// traversal is resolved within the root.
+		{"/assets/css/../../../index.html", http.StatusOK, "home"},
+		{"/assets/%2e%2e/%2e%2e/etc/passwd", http.StatusNotFound, "404 page not found"},
+		// unknown paths fall back to the index file for single page applications.
+		{"/app/orders/42", http.StatusOK, "home"},
+		{"/app/css/site.css", http.StatusOK, "body {}"},
+	}
+	for _, test := range tests {
+		w := serve(app.RootRouter, http.MethodGet, test.path)
+		if w.Code != test.status {
+			t.Errorf("GET %s: expected status %d, got %d", test.path, test.status, w.Code)
+			continue
+		}
+		if test.expected != "" && w.Body.String() != test.expected {
+			t.Errorf("GET %s: expected %q, got %q", test.path, test.expected, w.Body.String())
+		}
+	}
+
+	w := serve(app.RootRouter, http.MethodGet, "/assets/images/")
+	for _, expected := range []string{`<a href="logo.svg">logo.svg</a>`, `<a href="icon%201.png">icon 1.png</a>`} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("expected the listing to contain %q, got %q", expected, w.Body.String())
+		}
+	}
+
+	// conditional and range requests are handled by http.ServeContent.
+	w = serve(app.RootRouter, http.MethodGet, "/assets/downloads/menu.txt")
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected an ETag header")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/assets/downloads/menu.txt", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	app.RootRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304 for a matching ETag, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/assets/downloads/menu.txt", nil)
+	req.Header.Set("Range", "bytes=0-9")
+	w = httptest.NewRecorder()
+	app.RootRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "margherita" {
+		t.Errorf("expected 206 \"margherita\" for a range request, got %d %q", w.Code, w.Body.String())
+	}
+
+	// the prefix is matched like any other path.
+	folded := puff.App(&puff.AppConfig{Name: "static", CaseInsensitive: true})
+	folded.Static("/assets", assets)
+	if w := serve(folded.RootRouter, http.MethodGet, "/Assets/css/site.css"); w.Body.String() != "body {}" {
+		t.Errorf("expected the file under a case-insensitive prefix, got %d %q", w.Code, w.Body.String())
+	}
+
+	// directories on disk are served the same way.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	disk := puff.App(&puff.AppConfig{Name: "disk"})
+	disk.Static("/", dir)
+	if w := serve(disk.RootRouter, http.MethodGet, "/hello.txt"); w.Body.String() != "hello" {
+		t.Errorf("expected the file from disk, got %d %q", w.Code, w.Body.String())
+	}
+	if w := serve(disk.RootRouter, http.MethodGet, "/"); w.Code != http.StatusNotFound {
+		t.Errorf("expected directories without an index not to be listed by default, got %d", w.Code)
+	}
+}
+
+func TestRouterNested(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "nested", DocsURL: "/docs"})
+	tagging := func(name string) puff.Middleware {
+		return func(next puff.HandlerFunc) puff.HandlerFunc {
+			return func(c *puff.Context) {
+				c.ResponseWriter.Header().Add("X-Router", name)
+				next(c)
+			}
+		}
+	}
+	app.Use(tagging("app"))
+
+	// the tree is assembled bottom up, before any of it is included in the app.
+	v1 := puff.NewRouter("V1", "/v1")
+	v1.Use(tagging("v1"))
+	stores := puff.NewRouter("Stores", "/stores/{store:int}")
+	stores.Use(tagging("stores"))
+	menu := puff.NewRouter("Menu", "/menu")
+	menu.Use(tagging("menu"))
+	type menuItemInput struct {
+		Store int    `kind:"path"`
+		Item  string `kind:"path"`
+	}
+	menu.Get("/items/{item}", &menuItemInput{}, func(c *puff.Context) {
+		input := puff.Input[menuItemInput](c)
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprintf("store %d item %s", input.Store, input.Item)})
+	}).WithName("menu.item")
+	stores.IncludeRouter(menu)
+	v1.IncludeRouter(stores)
+	app.IncludeRouter(v1)
+
+	base := testlisten(t, app)
+	res, err := http.Get(base + "/v1/stores/12/menu/items/calzone")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "store 12 item calzone" {
+		t.Errorf("expected 200 %q, got %d %q", "store 12 item calzone", res.StatusCode, string(body))
+	}
+	if routers := res.Header.Values("X-Router"); len(routers) != 4 {
+		t.Errorf("expected the middlewares of all four routers to run, got %v", routers)
+	}
+
+	if url, err := app.URLFor("menu.item", 12, "calzone"); err != nil || url != "/v1/stores/12/menu/items/calzone" {
+		t.Errorf("expected URLFor to build the nested path, got %q %v", url, err)
+	}
+
+	// routes at every depth are documented.
+	if _, ok := app.OpenAPI.Paths["/v1/stores/{store}/menu/items/{item}"]; !ok {
+		t.Errorf("expected the nested route in the OpenAPI paths, got %v", app.OpenAPI.Paths)
+	}
+
+	// invalid input schemas deep in the tree fail startup.
+	broken := puff.App(&puff.AppConfig{Name: "broken"})
+	outer, inner := puff.NewRouter("Outer", "/outer"), puff.NewRouter("Inner", "/inner")
+	inner.Get("/", &struct {
+		Name string `kind:"nowhere"`
+	}{}, func(c *puff.Context) {})
+	outer.IncludeRouter(inner)
+	broken.IncludeRouter(outer)
+	if err := broken.ListenAndServe("127.0.0.1:0"); err == nil || !strings.Contains(err.Error(), "router Inner") {
+		t.Errorf("expected an input schema error for the nested route, got %v", err)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) puff.Middleware {
+		return func(next puff.HandlerFunc) puff.HandlerFunc {
+			return func(c *puff.Context) {
+				calls = append(calls, name+" before")
+				next(c)
+				calls = append(calls, name+" after")
+			}
+		}
+	}
+	app := puff.App(&puff.AppConfig{Name: "order"})
+	app.Use(record("app 1"))
+	app.Use(record("app 2"))
+	router := puff.NewRouter("Router", "/router")
+	router.Use(record("router 1"))
+	router.Use(record("router 2"))
+	sub := puff.NewRouter("Sub", "/sub")
+	sub.Use(record("sub"))
+	sub.Get("/route", nil, func(c *puff.Context) {
+		calls = append(calls, "handler")
+		c.SendResponse(puff.GenericResponse{Content: "ok"})
+	}).Use(record("route 1")).Use(record("route 2"))
+	sub.Get("/plain", nil, func(c *puff.Context) {
+		calls = append(calls, "handler")
+	})
+	router.IncludeRouter(sub)
+	app.IncludeRouter(router)
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"/router/sub/route", []string{
+			"app 1 before", "app 2 before", "router 1 before", "router 2 before", "sub before",
+			"route 1 before", "route 2 before", "handler", "route 2 after", "route 1 after",
+			"sub after", "router 2 after", "router 1 after", "app 2 after", "app 1 after",
+		}},
+		// route middlewares only apply to their route.
+		{"/router/sub/plain", []string{
+			"app 1 before", "app 2 before", "router 1 before", "router 2 before", "sub before",
+			"handler",
+			"sub after", "router 2 after", "router 1 after", "app 2 after", "app 1 after",
+		}},
+		// fallback handlers run inside the middlewares of the router that owns the path.
+		{"/router/missing", []string{
+			"app 1 before", "app 2 before", "router 1 before", "router 2 before",
+			"router 2 after", "router 1 after", "app 2 after", "app 1 after",
+		}},
+	}
+	for _, test := range tests {
+		calls = nil
+		serve(app.RootRouter, http.MethodGet, test.path)
+		if !slices.Equal(calls, test.expected) {
+			t.Errorf("GET %s: expected calls\n%v\ngot\n%v", test.path, test.expected, calls)
+		}
+	}
+
+	// serving again after the tree has been compiled does not apply middlewares twice.
+	calls = nil
+	testlisten(t, app)
+	serve(app.RootRouter, http.MethodGet, "/router/sub/plain")
+	if len(calls) != 11 {
+		t.Errorf("expected each middleware to run once after recompiling, got %v", calls)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "introspection"})
+	auth := func(next puff.HandlerFunc) puff.HandlerFunc { return next }
+	app.Get("/", nil, func(c *puff.Context) {})
+	menu := puff.NewRouter("Menu", "/menu")
+	menu.Use(auth)
+	menu.Match([]string{http.MethodPut, http.MethodPatch}, "/pizza/{id:int}/toppings/{topping}", nil, func(c *puff.Context) {}).WithName("toppings.update")
+	app.IncludeRouter(menu)
+
+	routes := app.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	info := routes[1]
+	if !slices.Equal(info.Methods, []string{http.MethodPut, http.MethodPatch}) ||
+		info.Path != "/menu/pizza/{id:int}/toppings/{topping}" ||
+		info.Name != "toppings.update" ||
+		info.Router != "Menu" ||
+		!slices.Equal(info.Params, []string{"id", "topping"}) ||
+		len(info.Middlewares) != 1 || !strings.HasPrefix(info.Middlewares[0], "puff_test.TestRoutes") {
+		t.Errorf("unexpected route info %+v", info)
+	}
+	if filepath.Base(info.File) != "router_test.go" || info.Line == 0 {
+		t.Errorf("expected the registration site of the route, got %s:%d", info.File, info.Line)
+	}
+
+	table := app.RouteTable()
+	for _, expected := range []string{"METHOD", "PUT,PATCH", "/menu/pizza/{id:int}/toppings/{topping}", "toppings.update", "router_test.go:"} {
+		if !strings.Contains(table, expected) {
+			t.Errorf("expected the route table to contain %q, got\n%s", expected, table)
+		}
+	}
+
+	dot := app.RootRouter.DOT()
+	for _, expected := range []string{"digraph puff {", `router1 [shape=box, label="Menu\n/menu"];`, "router0 -> router1;", "router1 -> route1;"} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("expected the DOT graph to contain %q, got\n%s", expected, dot)
+		}
+	}
+	mermaid := app.RootRouter.Mermaid()
+	for _, expected := range []string{"flowchart LR", `route0(["GET /"])`, "router0 --> router1"} {
+		if !strings.Contains(mermaid, expected) {
+			t.Errorf("expected the Mermaid flowchart to contain %q, got\n%s", expected, mermaid)
+		}
+	}
+}
+
+func TestRouterUpdate(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "runtime"})
+	app.Get("/pizza", nil, respond("pizza"))
+	base := testlisten(t, app)
+	get := func(path string) (int, string) {
+		res, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+
+	// requests keep being served while routes change.
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if status, body := get("/pizza"); status != http.StatusOK || body != "pizza" {
+					t.Errorf("expected the existing route to keep serving, got %d %q", status, body)
+					return
+				}
+			}
+		}
+	}()
+
+	plugin := puff.NewRouter("Plugin", "/plugins/pasta")
+	plugin.Use(func(next puff.HandlerFunc) puff.HandlerFunc {
+		return func(c *puff.Context) {
+			c.SetResponseHeader("X-Plugin", "pasta")
+			next(c)
+		}
+	})
+	menu := plugin.Get("/menu", nil, respond("pasta menu"))
+	plugin.Get("/specials", nil, respond("pasta specials"))
+	if err := app.Update(func() { app.IncludeRouter(plugin) }); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if status, body := get("/plugins/pasta/menu"); status != http.StatusOK || body != "pasta menu" {
+		t.Errorf("expected the route added at runtime to be served, got %d %q", status, body)
+	}
+	res, err := http.Get(base + "/plugins/pasta/specials")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	res.Body.Close()
+	if res.Header.Get("X-Plugin") != "pasta" {
+		t.Errorf("expected the middlewares of the plugin router to apply")
+	}
+
+	if err := app.Update(func() { plugin.Unregister(menu) }); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if status, _ := get("/plugins/pasta/menu"); status != http.StatusNotFound {
+		t.Errorf("expected the unregistered route to be gone, got %d", status)
+	}
+
+	// a conflicting update is rolled back as a whole.
+	err = app.Update(func() {
+		app.Get("/risotto", nil, respond("risotto"))
+		app.Get("/pizza", nil, respond("another pizza"))
+	})
+	if err == nil || !strings.Contains(err.Error(), "duplicate route GET /pizza") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+	if status, _ := get("/risotto"); status != http.StatusNotFound {
+		t.Errorf("expected the routes of a failed update not to be served, got %d", status)
+	}
+	if len(app.Routes()) != 2 {
+		t.Errorf("expected the routes of a failed update to be rolled back, got %d routes", len(app.Routes()))
+	}
+
+	if err := app.Update(func() { app.RootRouter.ExcludeRouter(plugin) }); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if status, _ := get("/plugins/pasta/specials"); status != http.StatusNotFound {
+		t.Errorf("expected the routes of an excluded router to be gone, got %d", status)
+	}
+
+	close(done)
+	<-stopped
+}
+
+func TestRouterUpdateMove(t *testing.T) {
+	tag := func(name string) puff.Middleware {
+		return func(next puff.HandlerFunc) puff.HandlerFunc {
+			return func(c *puff.Context) {
+				c.SetResponseHeader("X-Router", name)
+				next(c)
+			}
+		}
+	}
+	app := puff.App(&puff.AppConfig{Name: "moves"})
+	a, b := puff.NewRouter("A", "/a"), puff.NewRouter("B", "/b")
+	a.Use(tag("a"))
+	b.Use(tag("b"))
+	p := puff.NewRouter("P", "/p")
+	route := puff.Get(p, "/x/{id}", func(c *puff.Context, in *struct {
+		ID int `kind:"path"`
+	}) {
+		c.SendResponse(puff.GenericResponse{Content: fmt.Sprint(in.ID)})
+	})
+	a.IncludeRouter(p)
+	app.IncludeRouter(a)
+	app.IncludeRouter(b)
+	if err := app.Build(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expect := func(path string, status int, router string) {
+		t.Helper()
+		w := serve(app.RootRouter, http.MethodGet, path)
+		if w.Code != status || (router != "" && w.Header().Get("X-Router") != router) {
+			t.Errorf("GET %s: expected %d from router %q, got %d from router %q", path, status, router, w.Code, w.Header().Get("X-Router"))
+		}
+	}
+	expect("/a/p/x/1", http.StatusOK, "a")
+
+	// requests are served while the router moves back and forth, by the routes they matched.
+	done := make(chan struct{})
+	var wg, started sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(app.RootRouter, http.MethodGet, "/a/p/x/1")
+			started.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, path := range []string{"/a/p/x/1", "/b/p/x/1"} {
+					if w := serve(app.RootRouter, http.MethodGet, path); w.Code == http.StatusOK && w.Body.String() != "1" {
+						t.Errorf("GET %s: expected the bound id, got %q", path, w.Body.String())
+					}
+				}
+			}
+		}()
+	}
+	started.Wait()
+	for i := range 100 {
+		from, to := a, b
+		if i%2 == 1 {
+			from, to = b, a
+		}
+		if err := app.Update(func() {
+			from.ExcludeRouter(p)
+			to.IncludeRouter(p)
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	close(done)
+	wg.Wait()
+	expect("/a/p/x/1", http.StatusOK, "a")
+	expect("/b/p/x/1", http.StatusNotFound, "")
+
+	// a move that fails is rolled back, and the routes are served as before.
+	if err := app.Update(func() {
+		b.Get("/p/x/{id}", nil, respond("taken"))
+		a.ExcludeRouter(p)
+		b.IncludeRouter(p)
+	}); err == nil || !strings.Contains(err.Error(), "duplicate route GET /b/p/x/{id}") {
+		t.Fatalf("expected a duplicate route error, got %v", err)
+	}
+	expect("/a/p/x/1", http.StatusOK, "a")
+	expect("/b/p/x/1", http.StatusNotFound, "")
+	if err := app.Update(func() {
+		a.ExcludeRouter(p)
+		b.IncludeRouter(p)
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expect("/a/p/x/1", http.StatusNotFound, "")
+	expect("/b/p/x/1", http.StatusOK, "b")
+
+	// moved routes can still be unregistered with the route returned when registering them.
+	if err := app.Update(func() {
+		if !p.Unregister(route) {
+			t.Errorf("expected the moved route to be unregistered")
+		}
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expect("/b/p/x/1", http.StatusNotFound, "")
+}
+
+func TestRouterGroup(t *testing.T) {
+	type Problem struct {
+		Message string `json:"message"`
+	}
+	app := puff.App(&puff.AppConfig{Name: "groups", DocsURL: "/docs"})
+	app.Group("/admin", func(g *puff.Router) {
+		g.Tag = "Admin"
+		g.Description = "Restricted to staff."
+		g.Responses = puff.Responses{http.StatusForbidden: puff.ResponseType[Problem]}
+		g.Use(func(next puff.HandlerFunc) puff.HandlerFunc {
+			return func(c *puff.Context) {
+				c.SetResponseHeader("X-Admin", "yes")
+				next(c)
+			}
+		})
+		g.Get("/stats", nil, func(c *puff.Context) {
+			c.SendResponse(puff.GenericResponse{Content: "stats"})
+		})
+		g.Group("/users", func(g *puff.Router) {
+			g.Delete("/{id:int}", nil, func(c *puff.Context) {
+				c.SendResponse(puff.GenericResponse{Content: "deleted"})
+			})
+		})
+	})
+
+	base := testlisten(t, app)
+	req, _ := http.NewRequest(http.MethodDelete, base+"/admin/users/7", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "deleted" || res.Header.Get("X-Admin") != "yes" {
+		t.Errorf("expected the nested group route with the group middleware, got %q %v", string(body), res.Header)
+	}
+
+	stats, ok := app.OpenAPI.Paths["/admin/stats"]
+	if !ok || stats.Get == nil {
+		t.Fatalf("expected the group route in the OpenAPI paths, got %v", app.OpenAPI.Paths)
+	}
+	if !slices.Equal(stats.Get.Tags, []string{"Admin"}) {
+		t.Errorf("expected the group tag, got %v", stats.Get.Tags)
+	}
+	if !slices.ContainsFunc(app.OpenAPI.Tags, func(tag puff.Tag) bool {
+		return tag.Name == "Admin" && tag.Description == "Restricted to staff."
+	}) {
+		t.Errorf("expected the group tag and description, got %v", app.OpenAPI.Tags)
+	}
+	// group responses are inherited by nested groups.
+	deleteUser := app.OpenAPI.Paths["/admin/users/{id}"].Delete
+	if deleteUser == nil {
+		t.Fatalf("expected the nested group route in the OpenAPI paths")
+	}
+	if _, ok := deleteUser.Responses["403"]; !ok {
+		t.Errorf("expected the group responses on the nested route, got %v", deleteUser.Responses)
+	}
+}
@@ -0,0 +1,220 @@
+package puff
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// StaticConfig configures how Router.Static serves files.
+type StaticConfig struct {
+	// Index is the file served for a directory. Defaults to "index.html".
+	Index string
+	// Browse lists the contents of directories that do not have an index file.
+	// Directories are not listed by default.
+	Browse bool
+	// SPA serves the index file at the root of the file system for paths that do not
+	// exist, so a single page application can handle its own routing.
+	SPA bool
+}
+
+// DefaultStaticConfig is the configuration used by Router.Static if none is provided.
+var DefaultStaticConfig = StaticConfig{
+	Index: "index.html",
+}
+
+// Static serves the files in root under prefix. root is either a path to a directory
+// or an fs.FS, such as an embed.FS, so assets can be compiled into the binary.
+// Requests cannot escape root: paths are cleaned and ".." is never resolved past it.
+//
+// Files are served with http.ServeContent, which handles Range, If-Modified-Since and
+// If-None-Match requests. An ETag is set from the modification time and size of the
+// file, or from its content for file systems without modification times like embed.FS.
+// Static routes are not included in the OpenAPI documentation.
+//
+// Example usage:
+//
+//	//go:embed assets
+//	var assets embed.FS
+//
+//	sub, _ := fs.Sub(assets, "assets")
+//	app.Static("/assets", sub)
+//	app.Static("/", "./dist", puff.StaticConfig{SPA: true})
+func (r *Router) Static(prefix string, root any, config ...StaticConfig) {
+	var fsys fs.FS
+	switch root := root.(type) {
+	case string:
+		fsys = os.DirFS(root)
+	case fs.FS:
+		fsys = root
+	default:
+		panic(fmt.Sprintf("Static root for prefix %s must be a directory path or an fs.FS, got %T", prefix, root))
+	}
+	staticConfig := DefaultStaticConfig
+	if len(config) > 0 {
+		staticConfig = config[0]
+	}
+	if staticConfig.Index == "" {
+		staticConfig.Index = DefaultStaticConfig.Index
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	s := &staticFS{fsys: fsys, config: staticConfig, router: r}
+	for _, path := range []string{prefix, prefix + "/{filepath...}"} {
+		route := r.registerRoute(http.MethodGet, path, s.serve, nil)
+		route.hidden = true
+	}
+}
+
+// staticFS serves the files of a Router.Static call.
+type staticFS struct {
+	fsys   fs.FS
+	config StaticConfig
+	router *Router
+	// etags caches the content hashes of files without a modification time. Such file
+	// systems, like embed.FS, cannot change while the program runs.
+	etags sync.Map
+}
+
+func (s *staticFS) serve(c *Context) {
+	// the name is the rest of the path captured by the catch-all param, so it does not
+	// depend on how the prefix was matched.
+	name := c.catchAll
+	// cleaning a rooted path resolves every ".." without going above the root.
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		s.notFound(c)
+		return
+	}
+
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && s.config.SPA {
+			s.serveFile(c, s.config.Index)
+			return
+		}
+		s.notFound(c)
+		return
+	}
+	if !info.IsDir() {
+		s.serveFile(c, name)
+		return
+	}
+
+	// like http.FileServer, directories are served with a trailing slash so relative
+	// links in an index file or listing resolve within the directory.
+	if !strings.HasSuffix(c.Request.URL.Path, "/") {
+		c.SendResponse(RedirectResponse{
+			StatusCode: http.StatusMovedPermanently,
+			To:         path.Base(c.Request.URL.Path) + "/",
+		})
+		return
+	}
+	index := path.Join(name, s.config.Index)
+	if _, err := fs.Stat(s.fsys, index); err == nil {
+		s.serveFile(c, index)
+		return
+	}
+	if s.config.Browse {
+		s.list(c, name)
+		return
+	}
+	if s.config.SPA {
+		s.serveFile(c, s.config.Index)
+		return
+	}
+	s.notFound(c)
+}
+
+// serveFile writes the file name with http.ServeContent.
+func (s *staticFS) serveFile(c *Context, name string) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		s.notFound(c)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		s.notFound(c)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			c.InternalServerError("Failed to read file %s", name)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+	etag, err := s.etag(name, info, content)
+	if err != nil {
+		c.InternalServerError("Failed to read file %s", name)
+		return
+	}
+	c.SetResponseHeader("ETag", etag)
+	http.ServeContent(&statusWriter{ResponseWriter: c.ResponseWriter, c: c}, c.Request, info.Name(), info.ModTime(), content)
+}
+
+// etag returns a strong ETag for the file. Files with a modification time are tagged
+// from it and their size; others are hashed once and the result is cached.
+func (s *staticFS) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+	if etag, ok := s.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+// list writes an HTML listing of the directory name.
+func (s *staticFS) list(c *Context, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		c.InternalServerError("Failed to read directory %s", name)
+		return
+	}
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	b.WriteString("</pre>\n")
+	c.SendResponse(HTMLResponse{Content: b.String()})
+}
+
+// notFound runs the NotFound handler of the router serving the files.
+func (s *staticFS) notFound(c *Context) {
+	s.router.resolveNotFoundHandler()(c)
+}
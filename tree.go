@@ -0,0 +1,279 @@
+package puff
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// node is a single segment in the routing tree. The tree is a prefix tree keyed on
+// path segments and is built once from every route in the router tree, so a request
+// is matched by walking its path one segment at a time instead of testing each route.
+//
+// Static children are always preferred over param children. If a static branch fails
+// to produce a match further down, matching backtracks and tries the param branches.
+type node struct {
+	// static maps an exact segment to its child node.
+	static map[string]*node
+	// params holds the children that capture a segment. They are kept sorted so that
+	// the most specific pattern (constrained, then longest literal prefix + suffix) is
+	// tried first.
+	params []*node
+	// catchAll is the child capturing the rest of the path. It is tried last.
+	catchAll *node
+
+	// param describes what a param or catch-all node captures.
+	param pathParam
+
+	// routes maps a method to the routes registered at this node. There is more than one
+	// route per method only for versioned routes, sorted from the latest starting version.
+	routes map[string][]*Route
+}
+
+func newNode() *node {
+	return &node{
+		static: make(map[string]*node),
+		routes: make(map[string][]*Route),
+	}
+}
+
+// splitPath splits a full path into its segments. The leading slash is dropped so
+// "/pizza/{id}" becomes ["pizza", "{id}"] and "/" becomes [""].
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// insert adds route to the tree under its full path. It fails if the path is invalid or
+// if another route starting at the same API version is already registered for one of the
+// route's methods at the same node.
+func (n *node) insert(route *Route) error {
+	invalid := func(err error) error {
+		return fmt.Errorf("error building route %s %s registered at %s: %s", route.Protocol, route.fullPath, route.registeredAt(), err.Error())
+	}
+	current := n
+	segments := splitPath(route.fullPath)
+	for i, segment := range segments {
+		p, isParam, err := parsePathParam(segment)
+		if err != nil {
+			return invalid(err)
+		}
+		switch {
+		case !isParam:
+			child, ok := current.static[segment]
+			if !ok {
+				child = newNode()
+				current.static[segment] = child
+			}
+			current = child
+		case p.CatchAll:
+			if i != len(segments)-1 {
+				return invalid(fmt.Errorf("catch-all param %s must be the last segment of the path", p.Name))
+			}
+			if current.catchAll == nil {
+				current.catchAll = newNode()
+				current.catchAll.param = p
+			}
+			current = current.catchAll
+		default:
+			current = current.paramChild(p)
+		}
+	}
+	errs := []error{}
+	for _, method := range route.Methods() {
+		routes := current.routes[method]
+		i := slices.IndexFunc(routes, func(existing *Route) bool {
+			return existing.fromVersion == route.fromVersion
+		})
+		if i != -1 {
+			errs = append(errs, routeConflict(method, routes[i], route))
+			continue
+		}
+		routes = append(routes, route)
+		sort.SliceStable(routes, func(i, j int) bool {
+			return routes[i].fromVersion > routes[j].fromVersion
+		})
+		current.routes[method] = routes
+	}
+	return errors.Join(errs...)
+}
+
+// routeConflict describes two routes resolving to the same method and path.
+func routeConflict(method string, existing *Route, route *Route) error {
+	if existing.fullPath == route.fullPath {
+		return fmt.Errorf(
+			"duplicate route %s %s: registered at %s and again at %s",
+			method, route.fullPath, existing.registeredAt(), route.registeredAt(),
+		)
+	}
+	return fmt.Errorf(
+		"ambiguous routes %s %s registered at %s and %s %s registered at %s match the same requests",
+		method, existing.fullPath, existing.registeredAt(), method, route.fullPath, route.registeredAt(),
+	)
+}
+
+// checkRouteNames reports routes that share a name, since URLFor could only ever
+// build the URL of one of them.
+func checkRouteNames(routes []*Route) []error {
+	errs := []error{}
+	named := map[string]*Route{}
+	for _, route := range routes {
+		if route.Name == "" {
+			continue
+		}
+		if existing, ok := named[route.Name]; ok {
+			errs = append(errs, fmt.Errorf(
+				"duplicate route name %s: used by %s %s registered at %s and %s %s registered at %s",
+				route.Name, existing.Protocol, existing.fullPath, existing.registeredAt(), route.Protocol, route.fullPath, route.registeredAt(),
+			))
+			continue
+		}
+		named[route.Name] = route
+	}
+	return errs
+}
+
+// paramChild returns the child capturing p, creating it if needed. Params with the same
+// literals and constraint share a node regardless of their name.
+func (n *node) paramChild(p pathParam) *node {
+	for _, child := range n.params {
+		if child.param.Prefix == p.Prefix && child.param.Suffix == p.Suffix && child.param.Constraint == p.Constraint {
+			return child
+		}
+	}
+	child := newNode()
+	child.param = p
+	n.params = append(n.params, child)
+	sort.SliceStable(n.params, func(i, j int) bool {
+		pi, pj := n.params[i].param, n.params[j].param
+		if (pi.Constraint != "") != (pj.Constraint != "") {
+			return pi.Constraint != ""
+		}
+		return len(pi.Prefix)+len(pi.Suffix) > len(pj.Prefix)+len(pj.Suffix)
+	})
+	return child
+}
+
+// capture returns the value a param node captures from segment.
+func (n *node) capture(segment string) (string, bool) {
+	p := n.param
+	if len(segment) < len(p.Prefix)+len(p.Suffix) {
+		return "", false
+	}
+	if !strings.HasPrefix(segment, p.Prefix) || !strings.HasSuffix(segment, p.Suffix) {
+		return "", false
+	}
+	value := segment[len(p.Prefix) : len(segment)-len(p.Suffix)]
+	if !p.check(value) {
+		return "", false
+	}
+	return value, true
+}
+
+// route returns the route serving method for the API version at index version: the
+// route with the latest starting version whose range includes version.
+func (n *node) route(method string, version int) *Route {
+	for _, route := range n.routes[method] {
+		if route.servesVersion(version) {
+			return route
+		}
+	}
+	return nil
+}
+
+// servesVersion reports whether any route at the node serves the API version at index version.
+func (n *node) servesVersion(version int) bool {
+	for _, routes := range n.routes {
+		for _, route := range routes {
+			if route.servesVersion(version) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowed returns the methods a request to this node may use, in the format of the
+// Allow header. HEAD is allowed wherever GET is, and OPTIONS is always allowed.
+func (n *node) allowed() string {
+	methods := []string{}
+	for method := range n.routes {
+		methods = append(methods, method)
+	}
+	if _, ok := n.routes[http.MethodGet]; ok && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// router returns the router that owns the node. It is the router of the route registered
+// for the first method in alphabetical order, so the result is stable.
+func (n *node) router() *Router {
+	methods := []string{}
+	for method := range n.routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return n.routes[methods[0]][0].Router
+}
+
+// lookup finds the node that path resolves to for the API version at index version,
+// along with the captured path params in the order they appear in the path. Nodes whose
+// routes do not serve version are skipped, so matching backtracks to the branches that
+// do. It returns nil if no routes are registered for the path. If fold is true, static
+// segments are matched case-insensitively.
+func (n *node) lookup(path string, version int, fold bool) (*node, []string) {
+	return n.match(strings.TrimPrefix(path, "/"), nil, version, fold)
+}
+
+func (n *node) match(path string, params []string, version int, fold bool) (*node, []string) {
+	segment, rest, more := strings.Cut(path, "/")
+
+	if child, ok := n.static[segment]; ok {
+		if found, p := child.next(rest, more, params, version, fold); found != nil {
+			return found, p
+		}
+	}
+	if fold {
+		for key, child := range n.static {
+			if key == segment || !strings.EqualFold(key, segment) {
+				continue
+			}
+			if found, p := child.next(rest, more, params, version, fold); found != nil {
+				return found, p
+			}
+		}
+	}
+
+	for _, child := range n.params {
+		value, ok := child.capture(segment)
+		if !ok {
+			continue
+		}
+		if found, p := child.next(rest, more, append(params, value), version, fold); found != nil {
+			return found, p
+		}
+	}
+
+	if n.catchAll != nil && n.catchAll.servesVersion(version) {
+		return n.catchAll, append(params, path)
+	}
+	return nil, nil
+}
+
+// next continues matching from n once n has consumed a segment.
+func (n *node) next(rest string, more bool, params []string, version int, fold bool) (*node, []string) {
+	if more {
+		return n.match(rest, params, version, fold)
+	}
+	if !n.servesVersion(version) {
+		return nil, nil
+	}
+	return n, params
+}
@@ -0,0 +1,423 @@
+package puff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// rules are the validation rules declared in the struct tags of a field. They are
+// enforced on the values bound to the field and documented in its OpenAPI schema, so
+// the documentation and the validation cannot disagree. Rules on a slice apply to its
+// elements.
+type rules struct {
+	min        *float64
+	max        *float64
+	multipleOf *float64
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+	enum       []string
+	// format is validated for the "email" and "uuid" formats.
+	format string
+	// kind is the kind of the values the rules apply to.
+	kind reflect.Kind
+}
+
+// parseRules parses the rules declared in the tags of field.
+func parseRules(field reflect.StructField) (rules, error) {
+	r := rules{kind: ruleKind(field.Type)}
+	numeric := isAnyOfThese(r.kind, reflect.Int, reflect.Float64)
+	for _, tag := range []struct {
+		name string
+		dst  **float64
+	}{{"min", &r.min}, {"max", &r.max}, {"multipleOf", &r.multipleOf}} {
+		value, ok := field.Tag.Lookup(tag.name)
+		if !ok {
+			continue
+		}
+		if !numeric {
+			return r, fmt.Errorf("%s on field %s is only supported for numbers", tag.name, field.Name)
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return r, fmt.Errorf("%s on field %s must be a number", tag.name, field.Name)
+		}
+		*tag.dst = &n
+	}
+	if r.multipleOf != nil && *r.multipleOf <= 0 {
+		return r, fmt.Errorf("multipleOf on field %s must be greater than 0", field.Name)
+	}
+	for _, tag := range []struct {
+		name string
+		dst  **int
+	}{{"minLength", &r.minLength}, {"maxLength", &r.maxLength}} {
+		value, ok := field.Tag.Lookup(tag.name)
+		if !ok {
+			continue
+		}
+		if r.kind != reflect.String {
+			return r, fmt.Errorf("%s on field %s is only supported for strings", tag.name, field.Name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return r, fmt.Errorf("%s on field %s must be a non-negative integer", tag.name, field.Name)
+		}
+		*tag.dst = &n
+	}
+	if pattern, ok := field.Tag.Lookup("pattern"); ok {
+		if r.kind != reflect.String {
+			return r, fmt.Errorf("pattern on field %s is only supported for strings", field.Name)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return r, fmt.Errorf("pattern on field %s: %w", field.Name, err)
+		}
+		r.pattern = re
+	}
+	if enum, ok := field.Tag.Lookup("enum"); ok {
+		if r.kind != reflect.String && !numeric {
+			return r, fmt.Errorf("enum on field %s is only supported for strings and numbers", field.Name)
+		}
+		r.enum = strings.Split(enum, ",")
+		if numeric {
+			for _, value := range r.enum {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					return r, fmt.Errorf("enum on field %s must only contain numbers", field.Name)
+				}
+			}
+		}
+	}
+	if format := field.Tag.Get("format"); r.kind == reflect.String && (format == "email" || format == "uuid") {
+		r.format = format
+	}
+	return r, nil
+}
+
+// ruleKind returns the kind of the values of t the rules apply to: reflect.Int for
+// integers, reflect.Float64 for all numbers, reflect.String for strings and
+// reflect.Invalid for anything else. Pointers and slices are unwrapped.
+func ruleKind(t reflect.Type) reflect.Kind {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if _, ok := decoderFor(t); ok {
+		return reflect.Invalid
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Int
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.String:
+		return reflect.String
+	}
+	return reflect.Invalid
+}
+
+// document adds the rules to schema. The rules of a slice document its items.
+func (r rules) document(schema *Schema) {
+	if schema.Type == "array" && schema.Items != nil {
+		items := *schema.Items
+		r.document(&items)
+		schema.Items = &items
+		return
+	}
+	number := func(n *float64) json.Number {
+		if n == nil {
+			return ""
+		}
+		return json.Number(strconv.FormatFloat(*n, 'f', -1, 64))
+	}
+	if r.min != nil {
+		schema.Minimum = number(r.min)
+	}
+	if r.max != nil {
+		schema.Maximum = number(r.max)
+	}
+	schema.MultipleOf = number(r.multipleOf)
+	schema.MinLength = r.minLength
+	schema.MaxLength = r.maxLength
+	if r.pattern != nil {
+		schema.Pattern = r.pattern.String()
+	}
+	if r.enum != nil {
+		schema.Enum = []any{}
+		for _, value := range r.enum {
+			if r.kind == reflect.String {
+				schema.Enum = append(schema.Enum, value)
+			} else {
+				schema.Enum = append(schema.Enum, json.Number(value))
+			}
+		}
+	}
+	if r.format != "" {
+		schema.Format = r.format
+	}
+}
+
+// check returns the rule v breaks and why, or "" if it breaks none.
+func (r rules) check(v reflect.Value) (rule string, message string) {
+	switch r.kind {
+	case reflect.Int, reflect.Float64:
+		var n float64
+		switch {
+		case v.CanInt():
+			n = float64(v.Int())
+		case v.CanUint():
+			n = float64(v.Uint())
+		default:
+			n = v.Float()
+		}
+		formatted := strconv.FormatFloat(n, 'f', -1, 64)
+		if r.min != nil && n < *r.min {
+			return "min", fmt.Sprintf("must be at least %v", *r.min)
+		}
+		if r.max != nil && n > *r.max {
+			return "max", fmt.Sprintf("must be at most %v", *r.max)
+		}
+		if r.multipleOf != nil {
+			q := n / *r.multipleOf
+			if math.Abs(q-math.Round(q)) > 1e-9 {
+				return "multipleOf", fmt.Sprintf("must be a multiple of %v", *r.multipleOf)
+			}
+		}
+		if r.enum != nil && !slices.ContainsFunc(r.enum, func(value string) bool {
+			e, _ := strconv.ParseFloat(value, 64)
+			return e == n
+		}) {
+			return "enum", fmt.Sprintf("must be one of %s, got %s", strings.Join(r.enum, ", "), formatted)
+		}
+	case reflect.String:
+		s := v.String()
+		length := utf8.RuneCountInString(s)
+		if r.minLength != nil && length < *r.minLength {
+			return "minLength", fmt.Sprintf("must be at least %d characters long", *r.minLength)
+		}
+		if r.maxLength != nil && length > *r.maxLength {
+			return "maxLength", fmt.Sprintf("must be at most %d characters long", *r.maxLength)
+		}
+		if r.pattern != nil && !r.pattern.MatchString(s) {
+			return "pattern", fmt.Sprintf("must match %s", r.pattern.String())
+		}
+		if r.enum != nil && !slices.Contains(r.enum, s) {
+			return "enum", fmt.Sprintf("must be one of %s, got %s", strings.Join(r.enum, ", "), s)
+		}
+		switch r.format {
+		case "email":
+			if address, err := mail.ParseAddress(s); err != nil || address.Address != s {
+				return "email", "must be an email address"
+			}
+		case "uuid":
+			if !isUUID(s) {
+				return "uuid", "must be a UUID"
+			}
+		}
+	}
+	return "", ""
+}
+
+// fieldRules are the rules of a field of a struct bound from JSON.
+type fieldRules struct {
+	index int
+	name  string
+	rules rules
+}
+
+// structRulesCache caches the rules of the fields of struct types.
+var structRulesCache sync.Map
+
+// structRules returns the rules of the exported fields of the struct type t, which are
+// named like in JSON.
+func structRules(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := structRulesCache.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	fields := []fieldRules{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		r, err := parseRules(field)
+		if err != nil {
+			return nil, err
+		}
+		name := field.Name
+		if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName != "" {
+			name = jsonName
+		}
+		fields = append(fields, fieldRules{index: i, name: name, rules: r})
+	}
+	structRulesCache.Store(t, fields)
+	return fields, nil
+}
+
+// checkRules returns an error if the rules of any field of t, or of the structs
+// nested in t, are invalid.
+func checkRules(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if _, ok := decoderFor(t); ok || t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	if _, err := structRules(t); err != nil {
+		return err
+	}
+	for i := range t.NumField() {
+		if field := t.Field(i); field.IsExported() {
+			if err := checkRules(field.Type, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateValue enforces r on the value v of the param in, or of the field at path
+// within it, and the rules of the fields of v if it is a struct, returning every error
+// found. Structs implementing Validator are then validated with their hook, if their
+// fields are valid.
+func validateValue(c *Context, in string, path string, r rules, v reflect.Value) []*FieldError {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if _, ok := decoderFor(v.Type()); ok {
+		return nil
+	}
+	var errs []*FieldError
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			errs = append(errs, validateValue(c, in, fmt.Sprintf("%s[%d]", path, i), r, v.Index(i))...)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			errs = append(errs, validateValue(c, in, fmt.Sprintf("%s.%v", path, iter.Key()), r, iter.Value())...)
+		}
+	case reflect.Struct:
+		// invalid rules are reported when the route is compiled.
+		fields, _ := structRules(v.Type())
+		for _, field := range fields {
+			errs = append(errs, validateValue(c, in, path+"."+field.name, field.rules, v.Field(field.index))...)
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		if validator, ok := asValidator(v); ok {
+			if err := validator.Validate(c); err != nil {
+				return scopeErrors(err, in, path)
+			}
+		}
+	default:
+		if rule, message := r.check(v); rule != "" {
+			errs = append(errs, &FieldError{In: in, Field: path, Rule: rule, Message: message})
+		}
+	}
+	return errs
+}
+
+// Validator is implemented by inputs, and by the types nested in the body of inputs,
+// to validate them beyond the rules in their tags, such as checks across fields. The
+// hook is called once the value is bound and its rules hold, and can use the request
+// context, e.g. for tenant-specific rules.
+//
+// To report errors for specific fields, return a *FieldError naming the field, or
+// several joined with errors.Join. The Field of the errors of a nested type is relative
+// to it, and In may be left empty. The errors are sent with the other errors of the
+// request in a *ValidationError.
+//
+// Example usage:
+//
+//	func (in *BookingInput) Validate(c *puff.Context) error {
+//		if !in.Body.End.After(in.Body.Start) {
+//			return &puff.FieldError{Field: "Body.end", Rule: "after", Message: "must be after start"}
+//		}
+//		return nil
+//	}
+type Validator interface {
+	Validate(c *Context) error
+}
+
+// asValidator returns v as a Validator if it, or a pointer to it, implements it.
+func asValidator(v reflect.Value) (Validator, bool) {
+	if v.CanAddr() {
+		if validator, ok := v.Addr().Interface().(Validator); ok {
+			return validator, true
+		}
+	}
+	if v.CanInterface() {
+		validator, ok := v.Interface().(Validator)
+		return validator, ok
+	}
+	return nil, false
+}
+
+// scopeErrors scopes the errors returned by the Validator of the value at path within
+// the param in: the fields of FieldErrors are made relative to the param, and other
+// errors are reported for the value itself. Errors joined with errors.Join are scoped
+// individually.
+func scopeErrors(err error, in string, path string) []*FieldError {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := []*FieldError{}
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, scopeErrors(e, in, path)...)
+		}
+		return errs
+	}
+	var fieldError *FieldError
+	if !errors.As(err, &fieldError) {
+		return []*FieldError{{In: in, Field: path, Rule: "validate", Message: err.Error()}}
+	}
+	scoped := *fieldError
+	if scoped.In == "" {
+		scoped.In = in
+	}
+	switch {
+	case scoped.Field == "":
+		scoped.Field = path
+	case path != "":
+		scoped.Field = path + "." + scoped.Field
+	}
+	return []*FieldError{&scoped}
+}
+
+// validateInput calls the Validator of the input s, if it implements one. FieldErrors
+// naming a param without its kind are completed with the kind of the param.
+func validateInput(c *Context, s any, p []Parameter) error {
+	validator, ok := s.(Validator)
+	if !ok {
+		return nil
+	}
+	err := validator.Validate(c)
+	if err == nil {
+		return nil
+	}
+	errs := scopeErrors(err, "", "")
+	for _, fieldError := range errs {
+		if fieldError.In != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(fieldError.Field, ".")
+		name, _, _ = strings.Cut(name, "[")
+		if j := slices.IndexFunc(p, func(pa Parameter) bool { return pa.Name == name }); j != -1 {
+			fieldError.In = p[j].In
+		}
+	}
+	return &ValidationError{Errors: errs}
+}
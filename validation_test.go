@@ -0,0 +1,284 @@
+package puff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThePuffProject/puff"
+	"github.com/ThePuffProject/puff/middleware"
+)
+
+type Topping struct {
+	Name  string `json:"name" minLength:"2"`
+	Grams int    `json:"grams" min:"1" max:"200"`
+}
+
+type PizzaOrderInput struct {
+	Store    int      `kind:"path" min:"1"`
+	Size     string   `kind:"query" name:"size" enum:"small,medium,large"`
+	Slices   int      `kind:"query" name:"slices" multipleOf:"2" required:"false"`
+	Coupon   string   `kind:"header" name:"X-Coupon" pattern:"^[A-Z]{4}[0-9]{2}$" required:"false"`
+	Tags     []string `kind:"query" name:"tag" maxLength:"5" required:"false"`
+	Customer string   `kind:"cookie" name:"customer" format:"uuid" required:"false"`
+	Body     struct {
+		Email    string    `json:"email" format:"email"`
+		Toppings []Topping `json:"toppings"`
+	}
+}
+
+func TestValidation(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "validation", DocsURL: "/docs"})
+	puff.Post(app.RootRouter, "/stores/{store}/orders", func(c *puff.Context, in *PizzaOrderInput) {
+		c.SendResponse(puff.GenericResponse{Content: "ok"})
+	})
+
+	valid := `{"email": "luigi@example.com", "toppings": [{"name": "basil", "grams": 5}]}`
+	tests := []struct {
+		path     string
+		header   string
+		body     string
+		expected string
+	}{
+		{"/stores/1/orders?size=small&slices=8&tag=hot", "ABCD12", valid, "ok"},
+		{"/stores/0/orders?size=small", "", valid, `"location":"path","field":"Store","code":"min","message":"must be at least 1"`},
+		{"/stores/1/orders?size=huge", "", valid, `"field":"size","code":"enum","message":"must be one of small, medium, large, got huge"`},
+		{"/stores/1/orders?size=small&slices=3", "", valid, `"field":"slices","code":"multipleOf"`},
+		{"/stores/1/orders?size=small", "abcd12", valid, `"location":"header","field":"X-Coupon","code":"pattern"`},
+		{"/stores/1/orders?size=small&tag=hot&tag=smokey", "", valid, `"field":"tag[1]","code":"maxLength"`},
+		{"/stores/1/orders?size=small", "", `{"email": "luigi", "toppings": []}`, `"field":"Body.email","pointer":"/email","code":"email"`},
+		{"/stores/1/orders?size=small", "", `{"email": "luigi@example.com", "toppings": [{"name": "basil", "grams": 5}, {"name": "oil", "grams": 500}]}`, `"field":"Body.toppings[1].grams","pointer":"/toppings/1/grams","code":"max"`},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+		if test.header != "" {
+			req.Header.Set("X-Coupon", test.header)
+		}
+		w := httptest.NewRecorder()
+		app.RootRouter.ServeHTTP(w, req)
+		if !strings.Contains(w.Body.String(), test.expected) {
+			t.Errorf("POST %s: expected %q, got %d %q", test.path, test.expected, w.Code, w.Body.String())
+		}
+	}
+
+	base := testlisten(t, app)
+	res, err := http.Get(base + "/docs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	spec := puff.OpenAPI{}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("unexpected error decoding the spec: %s", err.Error())
+	}
+	schemas := map[string]puff.Schema{}
+	for _, param := range spec.Paths["/stores/{store}/orders"].Post.Parameters {
+		schemas[param.Name] = param.Schema
+	}
+	if schemas["Store"].Minimum != "1" {
+		t.Errorf("expected the minimum of Store to be documented, got %q", schemas["Store"].Minimum)
+	}
+	if enum := fmt.Sprint(schemas["size"].Enum); enum != "[small medium large]" {
+		t.Errorf("expected the enum of size to be documented, got %s", enum)
+	}
+	if schemas["slices"].MultipleOf != "2" {
+		t.Errorf("expected the multipleOf of slices to be documented, got %q", schemas["slices"].MultipleOf)
+	}
+	if schemas["X-Coupon"].Pattern != "^[A-Z]{4}[0-9]{2}$" {
+		t.Errorf("expected the pattern of X-Coupon to be documented, got %q", schemas["X-Coupon"].Pattern)
+	}
+	if schemas["customer"].Format != "uuid" {
+		t.Errorf("expected the format of customer to be documented, got %q", schemas["customer"].Format)
+	}
+	if items := schemas["tag"].Items; items == nil || items.MaxLength == nil || *items.MaxLength != 5 {
+		t.Errorf("expected the rules of a slice to document its items, got %+v", schemas["tag"].Items)
+	}
+	topping := spec.Components.Schemas["Topping"]
+	if topping == nil || topping.Properties["grams"].Minimum != "1" || topping.Properties["grams"].Maximum != "200" ||
+		topping.Properties["name"].MinLength == nil || *topping.Properties["name"].MinLength != 2 {
+		t.Errorf("expected the rules of nested body fields to be documented, got %+v", topping)
+	}
+}
+
+func TestValidationInvalidRules(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "invalid rules"})
+	puff.Get(app.RootRouter, "/pizza", func(c *puff.Context, in *struct {
+		Name string `kind:"query" min:"1"`
+	}) {
+	})
+	err := app.ListenAndServe("127.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "min on field Name is only supported for numbers") {
+		t.Errorf("expected an invalid rule error, got %v", err)
+	}
+}
+
+type Guest struct {
+	Email string `json:"email" required:"false"`
+	Phone string `json:"phone" required:"false"`
+}
+
+func (g Guest) Validate(c *puff.Context) error {
+	if g.Email == "" && g.Phone == "" {
+		return errors.Join(
+			&puff.FieldError{Field: "email", Rule: "required", Message: "or phone is required"},
+			&puff.FieldError{Field: "phone", Rule: "required", Message: "or email is required"},
+		)
+	}
+	return nil
+}
+
+type BookingInput struct {
+	Tenant string `kind:"header" name:"X-Tenant"`
+	Body   struct {
+		Start  int     `json:"start"`
+		End    int     `json:"end"`
+		Guests []Guest `json:"guests"`
+	}
+}
+
+func (in *BookingInput) Validate(c *puff.Context) error {
+	if in.Body.End <= in.Body.Start {
+		return &puff.FieldError{Field: "Body.end", Rule: "after", Message: "must be after start"}
+	}
+	// tenants can have their own rules.
+	if c.GetRequestHeader("X-Tenant") == "small-pizzeria" && len(in.Body.Guests) > 2 {
+		return &puff.FieldError{Field: "Body.guests", Rule: "maxGuests", Message: "must have at most 2 guests"}
+	}
+	return nil
+}
+
+func TestValidationHooks(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "validation hooks"})
+	puff.Post(app.RootRouter, "/bookings", func(c *puff.Context, in *BookingInput) {
+		c.SendResponse(puff.GenericResponse{Content: "ok"})
+	})
+
+	guest := `{"email": "luigi@example.com"}`
+	tests := []struct {
+		tenant   string
+		body     string
+		expected []string
+	}{
+		{"mario", `{"start": 1, "end": 2, "guests": [` + guest + `]}`, []string{"ok"}},
+		{"mario", `{"start": 2, "end": 1, "guests": []}`, []string{`"field":"Body.end","pointer":"/end","code":"after"`}},
+		{"mario", `{"start": 1, "end": 2, "guests": [` + guest + `, {}]}`, []string{
+			`"field":"Body.guests[1].email","pointer":"/guests/1/email","code":"required"`,
+			`"field":"Body.guests[1].phone","pointer":"/guests/1/phone","code":"required"`,
+		}},
+		{"small-pizzeria", `{"start": 1, "end": 2, "guests": [` + strings.Repeat(guest+",", 2) + guest + `]}`, []string{`"field":"Body.guests","pointer":"/guests","code":"maxGuests"`}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/bookings", strings.NewReader(test.body))
+		req.Header.Set("X-Tenant", test.tenant)
+		w := httptest.NewRecorder()
+		app.RootRouter.ServeHTTP(w, req)
+		for _, expected := range test.expected {
+			if !strings.Contains(w.Body.String(), expected) {
+				t.Errorf("%s %s: expected %q, got %d %q", test.tenant, test.body, expected, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestValidationProblem(t *testing.T) {
+	app := puff.App(&puff.AppConfig{Name: "validation problem", DocsURL: "/docs"})
+	puff.Post(app.RootRouter, "/stores/{store}/orders", func(c *puff.Context, in *PizzaOrderInput) {
+		c.SendResponse(puff.GenericResponse{Content: "ok"})
+	})
+	app.Get("/menu", nil, func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: "menu"})
+	})
+
+	// every error in the request is reported, not only the first.
+	w := serve(app.RootRouter, http.MethodPost, "/stores/zero/orders?size=huge&slices=3")
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected a 400 problem, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	problem := puff.ValidationProblem{}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("unexpected error decoding the problem: %s", err.Error())
+	}
+	codes := []string{}
+	for _, fieldError := range problem.Errors {
+		codes = append(codes, fieldError.In+" "+fieldError.Field+" "+fieldError.Rule)
+	}
+	expected := "[path Store invalid query size enum query slices multipleOf body Body required]"
+	if fmt.Sprint(codes) != expected {
+		t.Errorf("expected errors %s, got %s", expected, codes)
+	}
+	if problem.Status != http.StatusBadRequest || problem.Title != "Bad Request" || problem.Instance != "/stores/zero/orders" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+
+	// every invalid key of the body is reported, at its path within the body.
+	req := httptest.NewRequest(http.MethodPost, "/stores/1/orders?size=small", strings.NewReader(`{"email": 1, "toppings": [{"name": "basil", "grams": "five"}, {}], "crust": "thin"}`))
+	w = httptest.NewRecorder()
+	app.RootRouter.ServeHTTP(w, req)
+	problem = puff.ValidationProblem{}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("unexpected error decoding the problem: %s", err.Error())
+	}
+	pointers := []string{}
+	for _, fieldError := range problem.Errors {
+		pointers = append(pointers, fieldError.Pointer+" "+fieldError.Rule)
+	}
+	expected = "[/crust invalid /email invalid /toppings/0/grams invalid /toppings/1/grams required /toppings/1/name required]"
+	if fmt.Sprint(pointers) != expected {
+		t.Errorf("expected errors %s, got %s", expected, pointers)
+	}
+
+	base := testlisten(t, app)
+	res, err := http.Get(base + "/docs.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	spec := puff.OpenAPI{}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("unexpected error decoding the spec: %s", err.Error())
+	}
+	responses := spec.Paths["/stores/{store}/orders"].Post.Responses
+	for _, status := range []string{"400", "422"} {
+		if ref := responses[status].Content["application/problem+json"].Schema.Ref; ref != "#/components/schemas/ValidationProblem" {
+			t.Errorf("expected the %s response to be documented as a problem, got %q", status, ref)
+		}
+	}
+	if _, ok := spec.Paths["/menu"].Get.Responses["400"]; ok {
+		t.Errorf("expected routes without an input not to document validation problems")
+	}
+}
+
+func TestValidationProblemMiddlewares(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	app := puff.App(&puff.AppConfig{Name: "validation middlewares"})
+	app.Use(middleware.CORS())
+	app.Use(middleware.Logging())
+	puff.Post(app.RootRouter, "/stores/{store}/orders", func(c *puff.Context, in *PizzaOrderInput) {
+		c.SendResponse(puff.GenericResponse{Content: "ok"})
+	})
+
+	// problems are sent through the middlewares, so browsers can read them cross-origin
+	// and they are logged.
+	w := serve(app.RootRouter, http.MethodPost, "/stores/zero/orders?size=small")
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected a 400 problem, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Errorf("expected the CORS headers on the problem")
+	}
+	if !strings.Contains(logs.String(), "400") {
+		t.Errorf("expected the problem to be logged with its status, got %q", logs.String())
+	}
+}
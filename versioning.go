@@ -0,0 +1,249 @@
+package puff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Versioning configures how the API version of a request is resolved. The version is
+// taken from the first source that specifies one, in the order of the fields below,
+// and falls back to Default.
+//
+// Example usage:
+//
+//	app.Versioning = &puff.Versioning{
+//		Versions: []puff.APIVersion{
+//			{Name: "1", Deprecation: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+//			{Name: "2"},
+//		},
+//		URLPrefix:      true,             // /v1/pizza
+//		Header:         "Accept-Version", // Accept-Version: 1
+//		MediaTypeParam: "version",        // Accept: application/json; version=1
+//	}
+type Versioning struct {
+	// Versions are the supported versions, from oldest to latest.
+	Versions []APIVersion
+	// URLPrefix resolves the version from a leading "/v{version}" path segment, e.g.
+	// "/v2/pizza". The segment is stripped from the path before routing.
+	URLPrefix bool
+	// Header is the request header holding the version, e.g. "Accept-Version".
+	Header string
+	// MediaTypeParam is the parameter of the Accept header holding the version, e.g.
+	// "version" for "application/json; version=2".
+	MediaTypeParam string
+	// Default is the version of requests that do not specify one. Defaults to the latest version.
+	Default string
+}
+
+// APIVersion is a version of an API served with Versioning.
+type APIVersion struct {
+	// Name identifies the version in requests, e.g. "2" or "2024-06-01".
+	Name string
+	// Deprecation is when the version was, or will be, deprecated. If set, responses
+	// for the version carry a Deprecation header.
+	Deprecation time.Time
+	// Sunset is when the version will stop being served. If set, responses for the
+	// version carry a Sunset header.
+	Sunset time.Time
+	// Link is a URL describing the deprecation, sent in a Link header with the
+	// relation "deprecation".
+	Link string
+}
+
+// VersionRange is an inclusive range of API versions a router or route serves. An empty
+// From starts the range at the oldest version, and an empty To leaves it open, so the
+// route keeps serving later versions until a route starting at a later version replaces it.
+type VersionRange struct {
+	From string
+	To   string
+}
+
+// index returns the index of the version named name, or -1.
+func (v *Versioning) index(name string) int {
+	return slices.IndexFunc(v.Versions, func(version APIVersion) bool { return version.Name == name })
+}
+
+// defaultIndex returns the index of the version of requests that do not specify one.
+func (v *Versioning) defaultIndex() int {
+	if i := v.index(v.Default); i != -1 {
+		return i
+	}
+	return len(v.Versions) - 1
+}
+
+// resolve returns the index of the version req asks for. If the version is taken from
+// the path, the path prefix holding it is returned so it can be stripped.
+func (v *Versioning) resolve(req *http.Request) (version int, prefix string, err error) {
+	if v.URLPrefix {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		if name, ok := strings.CutPrefix(segment, "v"); ok {
+			if i := v.index(name); i != -1 {
+				return i, "/" + segment, nil
+			}
+		}
+	}
+	if v.Header != "" {
+		if name := req.Header.Get(v.Header); name != "" {
+			return v.named(name)
+		}
+	}
+	if v.MediaTypeParam != "" {
+		for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+			_, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+			if err != nil {
+				continue
+			}
+			if name, ok := params[v.MediaTypeParam]; ok {
+				return v.named(name)
+			}
+		}
+	}
+	return v.defaultIndex(), "", nil
+}
+
+func (v *Versioning) named(name string) (int, string, error) {
+	i := v.index(name)
+	if i == -1 {
+		return 0, "", fmt.Errorf("unsupported API version %s", name)
+	}
+	return i, "", nil
+}
+
+// vary returns the request headers the version is resolved from, for the Vary header.
+func (v *Versioning) vary() []string {
+	headers := []string{}
+	if v.Header != "" {
+		headers = append(headers, v.Header)
+	}
+	if v.MediaTypeParam != "" {
+		headers = append(headers, "Accept")
+	}
+	return headers
+}
+
+// writeHeaders sets the Deprecation, Sunset and Link headers of the version.
+func (version APIVersion) writeHeaders(h http.Header) {
+	if !version.Deprecation.IsZero() {
+		// RFC 9745 structured date.
+		h.Set("Deprecation", fmt.Sprintf("@%d", version.Deprecation.Unix()))
+	}
+	if !version.Sunset.IsZero() {
+		// RFC 8594 HTTP-date.
+		h.Set("Sunset", version.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if version.Link != "" {
+		h.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", version.Link))
+	}
+}
+
+// WithVersions restricts the route to the API versions from through to, inclusive.
+// Either may be empty to leave the range open. See VersionRange.
+//
+// Example usage:
+//
+//	app.Get("/pizza", nil, listPizzasV1).WithVersions("1", "1")
+//	app.Get("/pizza", nil, listPizzas).WithVersions("2", "")
+func (r *Route) WithVersions(from string, to string) *Route {
+	r.Versions = VersionRange{From: from, To: to}
+	return r
+}
+
+// resolveVersions resolves the version range of the route, or of the closest router
+// that sets one, to version indices.
+func (route *Route) resolveVersions() error {
+	route.fromVersion, route.toVersion = 0, math.MaxInt
+	versions := route.Versions
+	for current := route.Router; versions == (VersionRange{}) && current != nil; current = current.parent {
+		versions = current.Versions
+	}
+	if versions == (VersionRange{}) {
+		return nil
+	}
+	if route.Router.puff == nil || route.Router.puff.Versioning == nil {
+		return fmt.Errorf("route is restricted to versions %s to %s but versioning is not configured on the app", versions.From, versions.To)
+	}
+	v := route.Router.puff.Versioning
+	if versions.From != "" {
+		if route.fromVersion = v.index(versions.From); route.fromVersion == -1 {
+			return fmt.Errorf("unknown API version %s", versions.From)
+		}
+	}
+	if versions.To != "" {
+		if route.toVersion = v.index(versions.To); route.toVersion == -1 {
+			return fmt.Errorf("unknown API version %s", versions.To)
+		}
+	}
+	if route.fromVersion > route.toVersion {
+		return fmt.Errorf("API version %s is after %s", versions.From, versions.To)
+	}
+	return nil
+}
+
+// servesVersion reports whether the route serves the API version at index version.
+func (route *Route) servesVersion(version int) bool {
+	return version >= route.fromVersion && version <= route.toVersion
+}
+
+// APIVersion returns the name of the API version the request was resolved to, or "" if
+// the app does not use Versioning.
+func (ctx *Context) APIVersion() string {
+	return ctx.apiVersion
+}
+
+// OpenAPIFor returns the OpenAPI spec of the version named version: every documented
+// route serving the version, as it would be served for the version. It requires the
+// spec of the app to be generated, which ListenAndServe does.
+func (a *PuffApp) OpenAPIFor(version string) (*OpenAPI, error) {
+	if a.Versioning == nil {
+		return nil, fmt.Errorf("versioning is not configured on the app")
+	}
+	index := a.Versioning.index(version)
+	if index == -1 {
+		return nil, fmt.Errorf("unknown API version %s", version)
+	}
+	if a.OpenAPI == nil {
+		a.GenerateOpenAPISpec()
+	}
+	// the route serving each method and path for the version.
+	serving := map[string]*Route{}
+	for _, route := range a.RootRouter.AllRoutes() {
+		if route.hidden || !route.servesVersion(index) {
+			continue
+		}
+		key := route.Protocol + " " + route.fullPath
+		if current, ok := serving[key]; !ok || route.fromVersion > current.fromVersion {
+			serving[key] = route
+		}
+	}
+	paths, tags := a.generatePathsTags(func(route *Route) bool {
+		return serving[route.Protocol+" "+route.fullPath] == route
+	})
+
+	spec := *a.OpenAPI
+	spec.Info.Version = version
+	spec.Paths = paths
+	spec.Tags = tags
+	if a.Versioning.URLPrefix {
+		servers := []Server{}
+		if len(spec.Servers) == 0 {
+			servers = append(servers, Server{URL: "/v" + version})
+		}
+		for _, server := range spec.Servers {
+			server.URL = strings.TrimSuffix(server.URL, "/") + "/v" + version
+			servers = append(servers, server)
+		}
+		spec.Servers = servers
+	}
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	spec.spec = &b
+	return &spec, nil
+}
@@ -0,0 +1,168 @@
+package puff_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThePuffProject/puff"
+)
+
+// respondVersion returns a handler that responds with name, the API version of the
+// request and its path.
+func respondVersion(name string) func(*puff.Context) {
+	return func(c *puff.Context) {
+		c.SendResponse(puff.GenericResponse{Content: name + " for " + c.APIVersion() + " at " + c.Request.URL.Path})
+	}
+}
+
+func testversionedapp() *puff.PuffApp {
+	app := puff.App(&puff.AppConfig{
+		Name:    "versioned",
+		DocsURL: "/docs",
+		Versioning: &puff.Versioning{
+			Versions: []puff.APIVersion{
+				{
+					Name:        "1",
+					Deprecation: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					Sunset:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+					Link:        "https://example.com/migrate",
+				},
+				{Name: "2"},
+				{Name: "3"},
+			},
+			URLPrefix:      true,
+			Header:         "Accept-Version",
+			MediaTypeParam: "version",
+		},
+	})
+	app.Get("/pizza", nil, respondVersion("pizza v1")).WithVersions("1", "1")
+	app.Get("/pizza", nil, respondVersion("pizza v2")).WithVersions("2", "")
+	app.Get("/menu", nil, respondVersion("menu"))
+	app.Get("/pizza/{id}", nil, respondVersion("pizza by id"))
+	app.Get("/pizza/special", nil, respondVersion("special")).WithVersions("2", "")
+	specials := puff.NewRouter("Specials", "/specials")
+	specials.Versions = puff.VersionRange{From: "3"}
+	specials.Get("", nil, respondVersion("specials"))
+	app.IncludeRouter(specials)
+	return app
+}
+
+func TestVersioning(t *testing.T) {
+	app := testversionedapp()
+	tests := []struct {
+		path     string
+		header   http.Header
+		status   int
+		expected string
+	}{
+		{"/v1/pizza", nil, http.StatusOK, "pizza v1 for 1 at /pizza"},
+		{"/v2/pizza", nil, http.StatusOK, "pizza v2 for 2 at /pizza"},
+		// the latest compatible route serves later versions.
+		{"/v3/pizza", nil, http.StatusOK, "pizza v2 for 3 at /pizza"},
+		// requests without a version get the latest.
+		{"/pizza", nil, http.StatusOK, "pizza v2 for 3 at /pizza"},
+		{"/pizza", http.Header{"Accept-Version": {"1"}}, http.StatusOK, "pizza v1 for 1 at /pizza"},
+		{"/pizza", http.Header{"Accept": {"text/html, application/json; version=1"}}, http.StatusOK, "pizza v1 for 1 at /pizza"},
+		// the path takes precedence over headers.
+		{"/v2/pizza", http.Header{"Accept-Version": {"1"}}, http.StatusOK, "pizza v2 for 2 at /pizza"},
+		{"/v1/menu", nil, http.StatusOK, "menu for 1 at /menu"},
+		// static routes that do not serve the version fall back to params that do.
+		{"/pizza/special", http.Header{"Accept-Version": {"1"}}, http.StatusOK, "pizza by id for 1 at /pizza/special"},
+		{"/v1/pizza/special", nil, http.StatusOK, "pizza by id for 1 at /pizza/special"},
+		{"/v2/pizza/special", nil, http.StatusOK, "special for 2 at /pizza/special"},
+		{"/v3/specials", nil, http.StatusOK, "specials for 3 at /specials"},
+		{"/v2/specials", nil, http.StatusNotFound, ""},
+		{"/pizza", http.Header{"Accept-Version": {"9"}}, http.StatusBadRequest, ""},
+		// unknown versions in the path are not version prefixes.
+		{"/v9/pizza", nil, http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		for key, values := range test.header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		app.RootRouter.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("GET %s %v: expected status %d, got %d", test.path, test.header, test.status, w.Code)
+			continue
+		}
+		if test.expected != "" && w.Body.String() != test.expected {
+			t.Errorf("GET %s %v: expected %q, got %q", test.path, test.header, test.expected, w.Body.String())
+		}
+	}
+
+	w := serve(app.RootRouter, http.MethodGet, "/v1/pizza")
+	if w.Header().Get("Deprecation") != "@1735689600" ||
+		w.Header().Get("Sunset") != "Thu, 01 Jan 2026 00:00:00 GMT" ||
+		w.Header().Get("Link") != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("expected deprecation headers for version 1, got %v", w.Header())
+	}
+	if vary := w.Header().Values("Vary"); strings.Join(vary, ",") != "Accept-Version,Accept" {
+		t.Errorf("expected the version headers in Vary, got %v", vary)
+	}
+	if w := serve(app.RootRouter, http.MethodGet, "/v2/pizza"); w.Header().Get("Deprecation") != "" {
+		t.Errorf("expected no deprecation headers for version 2")
+	}
+}
+
+func TestVersioningConflicts(t *testing.T) {
+	app := puff.App(&puff.AppConfig{
+		Name:       "versioned conflicts",
+		Versioning: &puff.Versioning{Versions: []puff.APIVersion{{Name: "1"}, {Name: "2"}}},
+	})
+	// routes may share a path as long as they start at different versions.
+	app.Get("/pizza", nil, func(c *puff.Context) {}).WithVersions("1", "1")
+	app.Get("/pizza", nil, func(c *puff.Context) {}).WithVersions("2", "")
+	app.Get("/pizza", nil, func(c *puff.Context) {}).WithVersions("2", "2")
+	err := app.ListenAndServe("127.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "duplicate route GET /pizza") {
+		t.Errorf("expected a conflict for routes starting at the same version, got %v", err)
+	}
+
+	app = puff.App(&puff.AppConfig{
+		Name:       "unknown versions",
+		Versioning: &puff.Versioning{Versions: []puff.APIVersion{{Name: "1"}, {Name: "2"}}},
+	})
+	app.Get("/pasta", nil, func(c *puff.Context) {}).WithVersions("4", "")
+	err = app.ListenAndServe("127.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "unknown API version 4") {
+		t.Errorf("expected an unknown version error, got %v", err)
+	}
+}
+
+func TestVersioningOpenAPI(t *testing.T) {
+	app := testversionedapp()
+	base := testlisten(t, app)
+	for version, expected := range map[string]struct {
+		specials bool
+	}{"1": {false}, "3": {true}} {
+		res, err := http.Get(base + "/docs/" + version + ".json")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		spec := puff.OpenAPI{}
+		if err := json.Unmarshal(body, &spec); err != nil {
+			t.Fatalf("version %s: unexpected error decoding the spec: %s", version, err.Error())
+		}
+		if spec.Info.Version != version {
+			t.Errorf("version %s: expected the info version to be the API version, got %s", version, spec.Info.Version)
+		}
+		if _, ok := spec.Paths["/pizza"]; !ok {
+			t.Errorf("version %s: expected /pizza in the spec", version)
+		}
+		if _, ok := spec.Paths["/specials"]; ok != expected.specials {
+			t.Errorf("version %s: expected /specials in the spec to be %t", version, expected.specials)
+		}
+		if len(spec.Servers) == 0 || !strings.HasSuffix(spec.Servers[0].URL, "/v"+version) {
+			t.Errorf("version %s: expected the servers to include the version prefix, got %v", version, spec.Servers)
+		}
+	}
+}